@@ -0,0 +1,78 @@
+package main
+
+import "testing"
+
+// Diffs match what diff -u prints: three lines of context, hunks merged
+// when their context would meet, and missing final newlines marked
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name, before, after, want string
+	}{
+		{"same", "a\nb\n", "a\nb\n", ""},
+		{"one change", "1\n2\n3\n4\n5\n6\n7\n8\n", "1\n2\n3\n4\nfive\n6\n7\n8\n", `--- a/f
++++ b/f
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`},
+		{"separate hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n", "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n", `--- a/f
++++ b/f
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+`},
+		{"nearby changes share a hunk", "1\n2\n3\n4\n5\n6\n7\n8\n", "one\n2\n3\n4\n5\n6\n7\neight\n", `--- a/f
++++ b/f
+@@ -1,8 +1,8 @@
+-1
++one
+ 2
+ 3
+ 4
+ 5
+ 6
+ 7
+-8
++eight
+`},
+		{"lines added and removed", "a\nb\nc\n", "a\nx\ny\nc\nd\n", `--- a/f
++++ b/f
+@@ -1,3 +1,5 @@
+ a
+-b
++x
++y
+ c
++d
+`},
+		{"no newline at end", "a\nb", "a\nc", `--- a/f
++++ b/f
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`},
+	}
+	for _, tt := range tests {
+		if got := unifiedDiff("f", tt.before, tt.after); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
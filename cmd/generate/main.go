@@ -2,13 +2,24 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"hash/fnv"
+	"maps"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
-  "math/rand/v2"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
 	"dconn.dev/internal/generation"
+	"dconn.dev/internal/models"
 )
 
+// Chunk seeds are drawn from this range
 var minimum, maximum int = 10000, 99999
 
 // WorldConfig defines the entire world layout
@@ -16,10 +27,13 @@ var worldConfig = []generation.ChunkConfig{
 	{
 		ChunkX:      0,
 		ChunkY:      0,
-		Seed:        uint64(rand.IntN(maximum-minimum+1) + minimum),
 		Biome:       generation.BiomeGrassland,
 		Shorelines:  []generation.Direction{},
 		Connections: []generation.Direction{generation.South, generation.East, generation.West},
+		Rivers: []generation.RiverConfig{
+			// Rises in the northern hills and flows east to the sea at Port Silicon
+			{Entry: generation.North, EntryOffset: 36, Exit: generation.East, ExitOffset: 32, Width: 3, Meander: 4},
+		},
 		SignpostHints: map[generation.Direction]string{
 			generation.South: "Castle spires glimmer in the distance.",
 			generation.East:  "The smell of salt and sea beckons.",
@@ -34,11 +48,27 @@ var worldConfig = []generation.ChunkConfig{
 				Size:        2,
 			},
 		},
+		Zones: []generation.ZoneConfig{
+			{
+				Name:        "The Old Grove",
+				Description: "Trees older than any commit history. Someone has carved initials into the bark.",
+				Type:        generation.ZoneTypeLore,
+				Bounds:      generation.Bounds{MinX: 5, MinY: 5, MaxX: 11, MaxY: 11},
+				Mask: []string{
+					"...#...",
+					"..###..",
+					".#####.",
+					"#######",
+					".#####.",
+					"..###..",
+					"...#...",
+				},
+			},
+		},
 	},
 	{
 		ChunkX:      -1,
 		ChunkY:      -1,
-		Seed:        uint64(rand.IntN(maximum-minimum+1) + minimum),
 		Biome:       generation.BiomeMountain,
 		Shorelines:  []generation.Direction{generation.West, generation.North, generation.East},
 		Connections: []generation.Direction{generation.South},
@@ -52,6 +82,7 @@ var worldConfig = []generation.ChunkConfig{
 				Description: "Ancient runes are carved into the walls. They speak of transformations... of text becoming power.",
 				Structure:   "tower",
 				Size:        2,
+				Artifacts:   []string{"Lexer", "Parser", "Type Checker", "Code Generator"},
 			},
 			{
 				ProjectID:   "arithmetic-rdp",
@@ -65,7 +96,6 @@ var worldConfig = []generation.ChunkConfig{
 	{
 		ChunkX:      -1,
 		ChunkY:      0,
-		Seed:        uint64(rand.IntN(maximum-minimum+1) + minimum),
 		Biome:       generation.BiomeForest,
 		Shorelines:  []generation.Direction{generation.West},
 		Connections: []generation.Direction{generation.North, generation.South, generation.East},
@@ -94,10 +124,12 @@ var worldConfig = []generation.ChunkConfig{
 	{
 		ChunkX:      1,
 		ChunkY:      0,
-		Seed:        uint64(rand.IntN(maximum-minimum+1) + minimum),
 		Biome:       generation.BiomeCoastal,
 		Shorelines:  []generation.Direction{generation.East},
 		Connections: []generation.Direction{generation.West, generation.South},
+		Rivers: []generation.RiverConfig{
+			{Entry: generation.West, EntryOffset: 32, Exit: generation.East, ExitOffset: 36, Width: 3, Meander: 4},
+		},
 		SignpostHints: map[generation.Direction]string{
 			generation.West:  "Return to the peaceful starting meadows.",
 			generation.South: "Towers of healing rise to the south.",
@@ -115,7 +147,6 @@ var worldConfig = []generation.ChunkConfig{
 	{
 		ChunkX:      -1,
 		ChunkY:      1,
-		Seed:        uint64(rand.IntN(maximum-minimum+1) + minimum),
 		Biome:       generation.BiomeUrban,
 		Shorelines:  []generation.Direction{generation.West, generation.South},
 		Connections: []generation.Direction{generation.North, generation.East},
@@ -136,7 +167,6 @@ var worldConfig = []generation.ChunkConfig{
 	{
 		ChunkX:      0,
 		ChunkY:      1,
-		Seed:        uint64(rand.IntN(maximum-minimum+1) + minimum),
 		Biome:       generation.BiomeCastle,
 		Shorelines:  []generation.Direction{generation.South},
 		Connections: []generation.Direction{generation.North, generation.West, generation.East},
@@ -179,7 +209,6 @@ var worldConfig = []generation.ChunkConfig{
 	{
 		ChunkX:      1,
 		ChunkY:      1,
-		Seed:        uint64(rand.IntN(maximum-minimum+1) + minimum),
 		Biome:       generation.BiomeUrban,
 		Shorelines:  []generation.Direction{generation.East, generation.South},
 		Connections: []generation.Direction{generation.North, generation.West},
@@ -200,13 +229,31 @@ var worldConfig = []generation.ChunkConfig{
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: generate <output-dir>")
-		fmt.Println("       generate <output-dir> <chunk-x> <chunk-y>  (generate single chunk)")
+	if len(os.Args) > 1 && os.Args[1] == "projects" {
+		os.Exit(runProjects(os.Args[2:]))
+	}
+
+	trace := flag.Bool("trace", false, "write a generation trace next to each chunk")
+	attempts := flag.Int("attempts", 5, "seeds to try per chunk before giving up")
+	candidates := flag.Int("candidates", 1, "successful seeds to score per chunk, keeping the best")
+	workers := flag.Int("workers", runtime.NumCPU(), "chunks to generate in parallel")
+	legacy := flag.Bool("legacy", false, "draw with the original LCG and Taylor-series trig (old seeds still won't give the old worlds)")
+	size := flag.Int("size", generation.DefaultChunkSize, "tiles per side of each chunk")
+	layers := flag.Bool("layers", false, "add ground, overlay, collision, owner and elevation layers to each chunk")
+	strict := flag.Bool("strict", false, "fail if the world and projects.json disagree, rather than warning")
+	seed := flag.Uint64("seed", 0, "world seed that every chunk's seed is derived from, so a world can be drawn again (0 picks one)")
+	keepSeeds := flag.Bool("keep-seeds", false, "redraw each chunk from the seed and RNG stored in its existing file, where there is one")
+	palette := flag.String("palette", generation.DefaultPaletteName, "tile palette theme: "+strings.Join(generation.PaletteNames(), ", "))
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Println("Usage: generate [flags] <output-dir>")
+		fmt.Println("       generate [flags] <output-dir> <chunk-x> <chunk-y>  (generate single chunk)")
+		fmt.Println("       generate projects sync [flags] <repos-dir>  (propose catalogue updates from local clones)")
 		os.Exit(1)
 	}
 
-	outputDir := os.Args[1]
+	outputDir := flag.Arg(0)
 
 	// Ensure output directory exists
 	chunksDir := filepath.Join(outputDir, "chunks")
@@ -215,34 +262,445 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Generate chunks
-	for _, config := range worldConfig {
-		fmt.Printf("Generating chunk (%d, %d) - %s biome...\n", config.ChunkX, config.ChunkY, config.Biome)
+	policy := generation.RetryPolicy{
+		MaxAttempts: *attempts,
+		Candidates:  *candidates,
+		Trace:       *trace,
+	}
 
-		gen := generation.NewChunkGenerator(&config)
-		chunk, err := gen.Generate()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "  ERROR: %v\n", err)
-			continue
+	worldSeed := *seed
+	if worldSeed == 0 {
+		worldSeed = uint64(rand.IntN(maximum-minimum+1) + minimum)
+	}
+	fmt.Printf("World seed %d\n", worldSeed)
+
+	for i := range worldConfig {
+		worldConfig[i] = worldConfig[i].ScaledTo(*size)
+		worldConfig[i].Seed = chunkSeed(worldSeed, worldConfig[i].ChunkX, worldConfig[i].ChunkY)
+		worldConfig[i].Legacy = *legacy
+		if *keepSeeds {
+			if err := keepSeed(chunksDir, &worldConfig[i]); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read chunk seed: %v\n", err)
+				os.Exit(1)
+			}
 		}
+		worldConfig[i].Palette = *palette
+		worldConfig[i].Layers = *layers
+	}
 
-		// Write to file
-		filename := fmt.Sprintf("%d_%d.json", config.ChunkX, config.ChunkY)
-		path := filepath.Join(chunksDir, filename)
+	// The catalogue the world is checked against and signposted from. It's
+	// optional; without one neither happens.
+	var projects *models.ProjectList
+	if err := readJSON(filepath.Join(outputDir, "projects.json"), &projects); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load projects: %v\n", err)
+		os.Exit(1)
+	}
+	if projects != nil {
+		report := models.CheckConsistency(projects.Projects, worldPlacements(worldConfig))
+		for _, problem := range report.Problems() {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", problem)
+		}
+		if *strict && !report.OK() {
+			fmt.Fprintf(os.Stderr, "World and projects.json disagree (%d problems)\n", len(report.Problems()))
+			os.Exit(1)
+		}
+	}
 
-		data, err := json.MarshalIndent(chunk, "", "  ")
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "  ERROR marshaling JSON: %v\n", err)
-			continue
+	// Write the signposts from what the world knows: chunk names from the
+	// manifest and project titles from the catalogue
+	atlas, err := loadAtlas(outputDir, projects)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load signpost destinations: %v\n", err)
+		os.Exit(1)
+	}
+	destinations := atlas.Destinations(worldConfig)
+	for i := range worldConfig {
+		worldConfig[i].Destinations = destinations[generation.ChunkCoord{X: worldConfig[i].ChunkX, Y: worldConfig[i].ChunkY}]
+	}
+
+	// Work out every tile the world can draw before generating anything, so
+	// a bad palette or override fails fast
+	tiles, err := worldTiles(worldConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid palette: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Chunks are written to a staging directory and only moved into place
+	// once every one has succeeded, so a failed run never leaves the world
+	// half regenerated or out of step with world.json
+	staging, err := os.MkdirTemp(outputDir, ".chunks-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create staging directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Generate chunks concurrently. Each generator owns its RNG and grid, so
+	// the output doesn't depend on how many workers there are; reports are
+	// collected by index and printed in config order.
+	start := time.Now()
+	reports := make([]chunkReport, len(worldConfig))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(*workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				reports[i] = generateChunk(staging, worldConfig[i], policy)
+			}
+		}()
+	}
+	for i := range worldConfig {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+	for i, report := range reports {
+		config := worldConfig[i]
+		fmt.Printf("Generated chunk (%d, %d) - %s biome in %v\n", config.ChunkX, config.ChunkY, config.Biome, report.elapsed.Round(100*time.Microsecond))
+		for _, line := range report.log {
+			fmt.Printf("  %s\n", line)
 		}
+		if report.err == nil {
+			report.err = checkGlyphs(report.chunk, tiles)
+		}
+		if report.err != nil {
+			fmt.Fprintf(os.Stderr, "  ERROR: %v\n", report.err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		// Keep the traces, which explain the failures, but none of the chunks
+		if err := publish(staging, chunksDir, ".trace.json"); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write traces: %v\n", err)
+		}
+		os.RemoveAll(staging)
+		fmt.Fprintf(os.Stderr, "%d chunk(s) failed to generate; %s left unchanged\n", failed, chunksDir)
+		os.Exit(1)
+	}
+
+	err = publish(staging, chunksDir, "")
+	os.RemoveAll(staging)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to move chunks into place: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Keep the manifest's chunk size and tiles in step so the server and
+	// client follow
+	if err := updateManifest(filepath.Join(outputDir, "world.json"), *size, *palette, tiles); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update world.json: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Done in %v!\n", time.Since(start).Round(time.Millisecond))
+}
+
+// chunkSeed derives a chunk's seed from the world seed and its position
+func chunkSeed(world uint64, x, y int) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%d,%d", world, x, y)
+	return uint64(minimum) + h.Sum64()%uint64(maximum-minimum+1)
+}
 
-		if err := os.WriteFile(path, data, 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "  ERROR writing file: %v\n", err)
+// keepSeed sets a config's seed and RNG to those stored in its chunk's
+// existing file, so the chunk is drawn exactly as before. A missing file, or
+// one written before seeds were stored, leaves the config alone.
+func keepSeed(dir string, config *generation.ChunkConfig) error {
+	var stored struct {
+		Seed   uint64 `json:"seed"`
+		Legacy bool   `json:"legacy"`
+	}
+	filename := fmt.Sprintf("%d_%d.json", config.ChunkX, config.ChunkY)
+	if err := readJSON(filepath.Join(dir, filename), &stored); err != nil {
+		return err
+	}
+	if stored.Seed != 0 {
+		config.Seed = stored.Seed
+		config.Legacy = stored.Legacy
+	}
+	return nil
+}
+
+// publish moves the files in staging whose names end in suffix ("" for all)
+// into dir, replacing any already there
+func publish(staging, dir, suffix string) error {
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), suffix) {
 			continue
 		}
+		if err := os.Rename(filepath.Join(staging, e.Name()), filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		fmt.Printf("  Created %s (%d zones)\n", filename, len(chunk.Zones))
+// worldTiles resolves each chunk's palette and merges them into the tiles
+// the world manifest must define
+func worldTiles(configs []generation.ChunkConfig) ([]generation.PaletteTile, error) {
+	palettes := make([]*generation.Palette, 0, len(configs))
+	for _, config := range configs {
+		p, err := config.ResolvePalette()
+		if err != nil {
+			return nil, fmt.Errorf("chunk (%d, %d): %w", config.ChunkX, config.ChunkY, err)
+		}
+		palettes = append(palettes, p)
+	}
+	return generation.MergeTiles(palettes)
+}
+
+// checkGlyphs makes sure every glyph in a generated chunk has a tile
+// definition, so nothing reaches the client undrawable
+func checkGlyphs(chunk *generation.ChunkDefinition, tiles []generation.PaletteTile) error {
+	defined := make(map[string]bool, len(tiles))
+	for _, tile := range tiles {
+		defined[tile.Glyph] = true
+	}
+	for _, glyph := range chunk.Glyphs() {
+		if !defined[glyph] {
+			return fmt.Errorf("chunk uses glyph %q, which has no tile definition", glyph)
+		}
+	}
+	return nil
+}
+
+// loadAtlas reads the chunk names from world.json in the output directory
+// and takes the project titles from the catalogue. Either may be missing, in
+// which case signposts fall back to biome names and in-world names.
+func loadAtlas(outputDir string, projects *models.ProjectList) (generation.WorldAtlas, error) {
+	atlas := generation.WorldAtlas{
+		Names:    make(map[generation.ChunkCoord]string),
+		Projects: make(map[string]generation.AtlasProject),
+	}
+
+	var world models.World
+	if err := readJSON(filepath.Join(outputDir, "world.json"), &world); err != nil {
+		return atlas, err
+	}
+	for key, ref := range world.Chunks {
+		var coord generation.ChunkCoord
+		if _, err := fmt.Sscanf(key, "%d,%d", &coord.X, &coord.Y); err != nil {
+			return atlas, fmt.Errorf("world.json chunk key %q: %w", key, err)
+		}
+		atlas.Names[coord] = ref.Name
+	}
+
+	if projects != nil {
+		for _, p := range projects.Projects {
+			atlas.Projects[p.ID] = generation.AtlasProject{Title: p.Title, Featured: p.Featured}
+		}
+	}
+
+	return atlas, nil
+}
+
+// worldPlacements lists the projects the chunk configs place
+func worldPlacements(configs []generation.ChunkConfig) []models.Placement {
+	var placements []models.Placement
+	for _, config := range configs {
+		for _, proj := range config.Projects {
+			placements = append(placements, models.Placement{
+				ProjectID: proj.ProjectID,
+				Name:      proj.Name,
+				ChunkX:    config.ChunkX,
+				ChunkY:    config.ChunkY,
+			})
+		}
+	}
+	return placements
+}
+
+// readJSON parses a JSON file into v, leaving v untouched if the file
+// doesn't exist
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// Patterns for the values updateManifest edits in world.json
+var (
+	manifestSize    = regexp.MustCompile(`"chunk_size":\s*\d+`)
+	manifestSpawn   = regexp.MustCompile(`"spawn_local":\s*\[\s*\d+\s*,\s*\d+\s*\]`)
+	manifestPalette = regexp.MustCompile(`"palette":\s*"[^"]*"`)
+	manifestTiles   = regexp.MustCompile(`(?s)"tile_definitions":\s*\{.*?\n  \}`)
+)
+
+// updateManifest brings world.json in line with the generated chunks: the
+// chunk size (moving the spawn point to the middle of its chunk), the
+// palette name and the tile definitions. The values are edited in place so
+// the file's hand-written layout survives. A missing manifest is left alone.
+func updateManifest(path string, size int, palette string, tiles []generation.PaletteTile) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var world models.World
+	if err := json.Unmarshal(data, &world); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	original := string(data)
+
+	if world.ChunkSize != size {
+		data = manifestSize.ReplaceAll(data, []byte(fmt.Sprintf(`"chunk_size": %d`, size)))
+		data = manifestSpawn.ReplaceAll(data, []byte(fmt.Sprintf(`"spawn_local": [%d, %d]`, size/2, size/2)))
+		fmt.Printf("Updated world.json chunk size from %d to %d\n", world.ChunkSize, size)
+	}
+
+	if world.Palette != palette {
+		name, _ := json.Marshal(palette)
+		if manifestPalette.Match(data) {
+			data = manifestPalette.ReplaceAll(data, []byte(`"palette": `+string(name)))
+		} else {
+			data = manifestTiles.ReplaceAllFunc(data, func(block []byte) []byte {
+				return append([]byte(`"palette": `+string(name)+",\n  "), block...)
+			})
+		}
+		fmt.Printf("Updated world.json palette to %s\n", palette)
+	}
+
+	definitions := tileDefinitions(tiles)
+	if !maps.Equal(world.TileDefinitions, definitions) {
+		block := formatTileDefinitions(tiles)
+		data = manifestTiles.ReplaceAllFunc(data, func([]byte) []byte { return block })
+		fmt.Printf("Updated world.json tile definitions (%d tiles)\n", len(definitions))
+	}
+
+	if string(data) == original {
+		return nil
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// tileDefinitions converts palette tiles to the manifest's model
+func tileDefinitions(tiles []generation.PaletteTile) map[string]models.Tile {
+	defs := make(map[string]models.Tile, len(tiles))
+	for _, tile := range tiles {
+		defs[tile.Glyph] = models.Tile{
+			Character: tile.Char,
+			Color:     tile.Color,
+			Type:      tile.Type,
+			Walkable:  tile.Walkable,
+			Opaque:    tile.Opaque,
+		}
+	}
+	return defs
+}
+
+// formatTileDefinitions renders the tile_definitions block one tile per
+// line, the way world.json is laid out by hand
+func formatTileDefinitions(tiles []generation.PaletteTile) []byte {
+	var b strings.Builder
+	b.WriteString(`"tile_definitions": {`)
+	for i, tile := range tiles {
+		glyph, _ := json.Marshal(tile.Glyph)
+		char, _ := json.Marshal(tile.Char)
+		fmt.Fprintf(&b, "\n    %s: {\"char\": %s, \"color\": %q, \"type\": %q, \"walkable\": %t",
+			glyph, char, tile.Color, tile.Type, tile.Walkable)
+		if tile.Opaque {
+			b.WriteString(`, "opaque": true`)
+		}
+		b.WriteString("}")
+		if i < len(tiles)-1 {
+			b.WriteString(",")
+		}
+	}
+	b.WriteString("\n  }")
+	return []byte(b.String())
+}
+
+// chunkReport is the outcome of generating one chunk
+type chunkReport struct {
+	chunk   *generation.ChunkDefinition
+	log     []string
+	err     error
+	elapsed time.Duration
+}
+
+// generateChunk generates a chunk under the retry policy and writes it (and
+// its trace, if enabled) to dir
+func generateChunk(dir string, config generation.ChunkConfig, policy generation.RetryPolicy) (report chunkReport) {
+	start := time.Now()
+	defer func() { report.elapsed = time.Since(start) }()
+
+	result, err := generation.GenerateWithRetry(config, policy)
+
+	// The trace is most useful when generation fails, so write it first
+	if policy.Trace {
+		filename := fmt.Sprintf("%d_%d.trace.json", config.ChunkX, config.ChunkY)
+		if err := writeJSON(filepath.Join(dir, filename), result.Trace); err != nil {
+			report.log = append(report.log, fmt.Sprintf("Failed to write trace: %v", err))
+		} else {
+			report.log = append(report.log, "Wrote trace "+filename)
+		}
+	}
+
+	for _, failure := range result.Failures {
+		report.log = append(report.log, fmt.Sprintf("Attempt failed, %v", failure))
+	}
+	if err != nil {
+		report.err = err
+		return report
+	}
+
+	filename := fmt.Sprintf("%d_%d.json", config.ChunkX, config.ChunkY)
+	if err := writeJSON(filepath.Join(dir, filename), result.Chunk); err != nil {
+		report.err = err
+		return report
+	}
+
+	report.chunk = result.Chunk
+	report.log = append(report.log, fmt.Sprintf("Created %s (%d zones) with seed %d after %d attempt(s), score %.2f",
+		filename, len(result.Chunk.Zones), result.Seed, result.Attempts, result.Score.Total))
+	return report
+}
+
+// writeJSON writes v as indented JSON. The file is written under a temporary
+// name and renamed into place, so readers never see a half-written chunk.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", filepath.Base(path), err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", filepath.Base(path), err)
 	}
 
-	fmt.Println("Done!")
+	return os.Rename(tmp.Name(), path)
 }
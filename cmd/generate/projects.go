@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"dconn.dev/internal/markdown"
+	"dconn.dev/internal/models"
+)
+
+// languages names the language of source files by extension. Extensions
+// not listed (docs, data, build files) don't count towards the breakdown.
+var languages = map[string]string{
+	".go":    "Go",
+	".js":    "JavaScript",
+	".mjs":   "JavaScript",
+	".jsx":   "JavaScript",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".py":    "Python",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".c":     "C",
+	".h":     "C",
+	".cpp":   "C++",
+	".cc":    "C++",
+	".hpp":   "C++",
+	".cs":    "C#",
+	".rs":    "Rust",
+	".rb":    "Ruby",
+	".php":   "PHP",
+	".swift": "Swift",
+	".html":  "HTML",
+	".css":   "CSS",
+	".scss":  "CSS",
+	".sh":    "Shell",
+	".sql":   "SQL",
+	".lisp":  "Lisp",
+	".y":     "Yacc/Bison",
+	".l":     "Lex/Flex",
+}
+
+// Patterns for the values updateProject edits in projects.json
+var (
+	catalogueID          = regexp.MustCompile(`"id":\s*"((?:[^"\\]|\\.)*)"`)
+	catalogueYear        = regexp.MustCompile(`"year":\s*\d+`)
+	catalogueTech        = regexp.MustCompile(`"tech_stack":\s*\[[^\]]*\]`)
+	catalogueDescription = regexp.MustCompile(`"description":\s*"(?:[^"\\]|\\.)*"`)
+)
+
+// htmlTag matches an HTML tag or comment, which READMEs often use for badges
+// and layout
+var htmlTag = regexp.MustCompile(`(?s)<!--.*?-->|</?[A-Za-z][^<>]*>`)
+
+// repoInfo is what a local clone says about a project
+type repoInfo struct {
+	dir         string
+	first, last time.Time          // Commit dates
+	shares      map[string]float64 // Language to fraction of source bytes
+	summary     string             // First paragraph of the README
+}
+
+// runProjects handles "generate projects <command> ...", returning the exit
+// code
+func runProjects(args []string) int {
+	if len(args) == 0 || args[0] != "sync" {
+		fmt.Fprintln(os.Stderr, "Usage: generate projects sync [flags] <repos-dir>")
+		return 1
+	}
+
+	fs := flag.NewFlagSet("projects sync", flag.ExitOnError)
+	catalogue := fs.String("catalogue", "data/projects.json", "project catalogue to update")
+	fields := fs.String("fields", "year,tech", "fields to propose updates for: year, tech, description")
+	minShare := fs.Float64("min-share", 5, "percent of source a language needs to be added to the tech stack")
+	write := fs.Bool("write", false, "write the proposed catalogue instead of only printing the diff")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: generate projects sync [flags] <repos-dir>")
+		fmt.Fprintln(os.Stderr, "Reads local clones named after each project's id or GitHub repository and")
+		fmt.Fprintln(os.Stderr, "prints the catalogue changes they suggest as a unified diff.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	reposDir := fs.Arg(0)
+
+	want := make(map[string]bool)
+	for _, f := range strings.Split(*fields, ",") {
+		switch f = strings.TrimSpace(f); f {
+		case "year", "tech", "description":
+			want[f] = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown field %q; expected year, tech or description\n", f)
+			return 1
+		}
+	}
+
+	data, err := os.ReadFile(*catalogue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read catalogue: %v\n", err)
+		return 1
+	}
+	var projects models.ProjectList
+	if err := json.Unmarshal(data, &projects); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse %s: %v\n", *catalogue, err)
+		return 1
+	}
+
+	proposed := data
+	for _, p := range projects.Projects {
+		dir := findClone(reposDir, p)
+		if dir == "" {
+			fmt.Fprintf(os.Stderr, "%s: no clone found in %s\n", p.ID, reposDir)
+			continue
+		}
+		info, err := readRepo(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", p.ID, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s, commits %s to %s, %s\n", p.ID, info.dir,
+			info.first.Format("2006-01-02"), info.last.Format("2006-01-02"), formatShares(info.shares))
+
+		proposed = updateProject(proposed, p, info, want, *minShare/100)
+	}
+
+	if bytes.Equal(proposed, data) {
+		fmt.Fprintln(os.Stderr, "Catalogue is up to date")
+		return 0
+	}
+
+	fmt.Print(unifiedDiff(*catalogue, string(data), string(proposed)))
+	if *write {
+		if err := os.WriteFile(*catalogue, proposed, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write catalogue: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Updated %s\n", *catalogue)
+	}
+	return 0
+}
+
+// findClone returns the clone of a project in reposDir: a directory named
+// after its id or its GitHub repository. "" if there is none.
+func findClone(reposDir string, p models.Project) string {
+	names := []string{p.ID}
+	if p.GitHubURL != "" {
+		names = append(names, strings.TrimSuffix(path.Base(p.GitHubURL), ".git"))
+	}
+	for _, name := range names {
+		dir := filepath.Join(reposDir, name)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// readRepo gathers what a clone says about its project, using only the
+// working tree and its git history
+func readRepo(dir string) (*repoInfo, error) {
+	info := &repoInfo{dir: dir, shares: make(map[string]float64)}
+
+	dates, err := git(dir, "log", "--format=%cI")
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Fields(dates)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s has no commits", dir)
+	}
+	if info.last, err = time.Parse(time.RFC3339, lines[0]); err != nil {
+		return nil, err
+	}
+	if info.first, err = time.Parse(time.RFC3339, lines[len(lines)-1]); err != nil {
+		return nil, err
+	}
+
+	// Only tracked files count, so build output and dependencies don't
+	files, err := git(dir, "ls-files", "-z")
+	if err != nil {
+		return nil, err
+	}
+	total := 0.0
+	for _, name := range strings.Split(files, "\x00") {
+		lang, ok := languages[strings.ToLower(filepath.Ext(name))]
+		if !ok {
+			continue
+		}
+		stat, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			continue // Deleted but not committed
+		}
+		info.shares[lang] += float64(stat.Size())
+		total += float64(stat.Size())
+	}
+	for lang := range info.shares {
+		info.shares[lang] /= total
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := strings.ToLower(e.Name())
+		if !e.IsDir() && (name == "readme" || strings.HasPrefix(name, "readme.")) {
+			readme, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			info.summary = plainText(markdown.Summary(string(readme)))
+			break
+		}
+	}
+
+	return info, nil
+}
+
+// plainText strips the HTML a README summary may carry, since descriptions
+// are plain text: tags and comments go, as do any angle brackets left over
+func plainText(s string) string {
+	s = htmlTag.ReplaceAllLiteralString(s, " ")
+	s = strings.NewReplacer("<", "", ">", "").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// git runs a git command in a repository and returns its output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// sortedLanguages returns languages by share, largest first
+func sortedLanguages(shares map[string]float64) []string {
+	langs := make([]string, 0, len(shares))
+	for lang := range shares {
+		langs = append(langs, lang)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if shares[langs[i]] != shares[langs[j]] {
+			return shares[langs[i]] > shares[langs[j]]
+		}
+		return langs[i] < langs[j]
+	})
+	return langs
+}
+
+// formatShares describes a language breakdown, largest first
+func formatShares(shares map[string]float64) string {
+	if len(shares) == 0 {
+		return "no recognised source"
+	}
+	parts := make([]string, 0, len(shares))
+	for _, lang := range sortedLanguages(shares) {
+		parts = append(parts, fmt.Sprintf("%s %.0f%%", lang, shares[lang]*100))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// updateProject edits a project's entry in the catalogue to match its clone.
+// The year becomes that of the latest commit, languages with at least
+// minShare of the source are added to the tech stack (nothing is removed,
+// since frameworks and tools can't be detected), and the description becomes
+// the README's first paragraph. Values are edited in place so the file's
+// hand-written layout survives.
+func updateProject(catalogue []byte, p models.Project, info *repoInfo, want map[string]bool, minShare float64) []byte {
+	start, end := projectSpan(catalogue, p.ID)
+	if start < 0 {
+		return catalogue
+	}
+	entry := catalogue[start:end]
+
+	if want["year"] && info.last.Year() != p.Year {
+		entry = catalogueYear.ReplaceAllLiteral(entry, []byte(fmt.Sprintf(`"year": %d`, info.last.Year())))
+	}
+
+	if want["tech"] {
+		stack := append([]string(nil), p.TechStack...)
+		have := make(map[string]bool)
+		for _, t := range stack {
+			have[strings.ToLower(t)] = true
+		}
+		for _, lang := range sortedLanguages(info.shares) {
+			if info.shares[lang] >= minShare && !have[strings.ToLower(lang)] {
+				stack = append(stack, lang)
+			}
+		}
+		if len(stack) != len(p.TechStack) {
+			quoted := make([]string, len(stack))
+			for i, t := range stack {
+				quoted[i] = jsonString(t)
+			}
+			entry = catalogueTech.ReplaceAllLiteral(entry, []byte(`"tech_stack": [`+strings.Join(quoted, ", ")+`]`))
+		}
+	}
+
+	if want["description"] && info.summary != "" && info.summary != p.Description {
+		entry = catalogueDescription.ReplaceAllLiteral(entry, []byte(`"description": `+jsonString(info.summary)))
+	}
+
+	out := append([]byte(nil), catalogue[:start]...)
+	out = append(out, entry...)
+	return append(out, catalogue[end:]...)
+}
+
+// projectSpan finds the bytes of the catalogue holding a project's fields:
+// from its id to the next project's id. start is -1 if it isn't there.
+func projectSpan(catalogue []byte, id string) (start, end int) {
+	matches := catalogueID.FindAllSubmatchIndex(catalogue, -1)
+	for i, m := range matches {
+		var found string
+		if json.Unmarshal(catalogue[m[2]-1:m[3]+1], &found) != nil || found != id {
+			continue
+		}
+		end = len(catalogue)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		return m[0], end
+	}
+	return -1, -1
+}
+
+// jsonString quotes a string for JSON without escaping HTML characters
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
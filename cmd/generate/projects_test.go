@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"dconn.dev/internal/models"
+)
+
+// syncCatalogue has one project on single lines and one whose tech stack
+// spans several, as hand-written catalogues do
+const syncCatalogue = `{
+  "projects": [
+    {
+      "id": "first",
+      "description": "Old",
+      "tech_stack": ["Go"],
+      "year": 2020
+    },
+    {
+      "id": "multi",
+      "title": "Multi",
+      "description": "Spread over lines",
+      "tech_stack": [
+        "C",
+        "Make"
+      ],
+      "year": 2019
+    }
+  ]
+}
+`
+
+// Only the synced project's values change, replacements are taken literally
+// even where they hold "$", and the rest of the layout is kept
+func TestUpdateProject(t *testing.T) {
+	p := models.Project{ID: "multi", Description: "Spread over lines", TechStack: []string{"C", "Make"}, Year: 2019}
+	info := &repoInfo{
+		last:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		shares:  map[string]float64{"C$1": 0.5, "Go": 0.45, "Shell": 0.05},
+		summary: "Costs $1 or ${x} & more",
+	}
+	want := `{
+  "projects": [
+    {
+      "id": "first",
+      "description": "Old",
+      "tech_stack": ["Go"],
+      "year": 2020
+    },
+    {
+      "id": "multi",
+      "title": "Multi",
+      "description": "Costs $1 or ${x} & more",
+      "tech_stack": ["C", "Make", "C$1", "Go"],
+      "year": 2024
+    }
+  ]
+}
+`
+	all := map[string]bool{"year": true, "tech": true, "description": true}
+	if got := string(updateProject([]byte(syncCatalogue), p, info, all, 0.1)); got != want {
+		t.Errorf("updateProject =\n%s\nwant\n%s", got, want)
+	}
+
+	// Fields not asked for are left alone
+	if got := string(updateProject([]byte(syncCatalogue), p, info, map[string]bool{}, 0.1)); got != syncCatalogue {
+		t.Errorf("updateProject with no fields changed the catalogue:\n%s", got)
+	}
+}
+
+// A project's span runs from its id to the next project's, across lines,
+// and ids are compared unquoted
+func TestProjectSpan(t *testing.T) {
+	tests := []struct {
+		id, want string
+	}{
+		{"first", `"id": "first",
+      "description": "Old",
+      "tech_stack": ["Go"],
+      "year": 2020
+    },
+    {
+      `},
+		{"multi", `"id": "multi",
+      "title": "Multi",
+      "description": "Spread over lines",
+      "tech_stack": [
+        "C",
+        "Make"
+      ],
+      "year": 2019
+    }
+  ]
+}
+`},
+	}
+	for _, tt := range tests {
+		start, end := projectSpan([]byte(syncCatalogue), tt.id)
+		if start < 0 {
+			t.Errorf("%s: not found", tt.id)
+			continue
+		}
+		if got := syncCatalogue[start:end]; got != tt.want {
+			t.Errorf("%s: span =\n%q\nwant\n%q", tt.id, got, tt.want)
+		}
+	}
+
+	if start, _ := projectSpan([]byte(syncCatalogue), "missing"); start != -1 {
+		t.Errorf("missing project found at %d", start)
+	}
+	escaped := `{"projects": [{"id": "a\"b", "year": 1}]}`
+	if start, _ := projectSpan([]byte(escaped), `a"b`); start < 0 {
+		t.Error("escaped id not found")
+	}
+}
+
+// README summaries lose their markup, since descriptions are plain text
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"A Python disassembler & more <b>.", "A Python disassembler & more ."},
+		{`<img src="badge.svg" alt="build"> Fast <em>parser</em>`, "Fast parser"},
+		{"Line one<br/>line two", "Line one line two"},
+		{"Hidden <!-- a\ncomment --> text", "Hidden text"},
+		{"Uses x < y and y > z", "Uses x y and y z"},
+		{"Plain", "Plain"},
+	}
+	for _, tt := range tests {
+		if got := plainText(tt.in); got != tt.want {
+			t.Errorf("plainText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -3,17 +3,27 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"strconv"
 
 	"dconn.dev/internal/models"
 )
 
 // Config holds all application configuration
 type Config struct {
-	ServerAddr   string
-	DataPath     string
-	GameMap      *models.GameMap
-	Projects     *models.ProjectList
-	GameConfig   *GameConfig
+	ServerAddr string
+	DataPath   string
+	GameMap    *models.GameMap
+	Projects   *models.ProjectList
+	GameConfig *GameConfig
+
+	// StrictConsistency refuses to start when the world and projects.json
+	// disagree, rather than warning
+	StrictConsistency bool
+
+	// SessionDir keeps sessions as files so they survive restarts. Sessions
+	// are kept in memory when it's empty.
+	SessionDir string
 }
 
 // GameConfig holds game-specific settings
@@ -44,12 +54,17 @@ func Load() *Config {
 		serverAddr = ":8080"
 	}
 
+	strict, _ := strconv.ParseBool(os.Getenv("STRICT_CONSISTENCY"))
+
 	return &Config{
 		ServerAddr: serverAddr,
 		DataPath:   "data",
 		GameMap:    gameMap,
 		Projects:   projects,
 		GameConfig: gameConfig,
+
+		StrictConsistency: strict,
+		SessionDir:        os.Getenv("SESSION_DIR"),
 	}
 }
 
@@ -79,6 +94,19 @@ func loadProjects() *models.ProjectList {
 	if err := json.Unmarshal(data, &projects); err != nil {
 		panic("Failed to parse projects.json: " + err.Error())
 	}
+	if err := projects.Validate(); err != nil {
+		panic("Invalid projects.json: " + err.Error())
+	}
+
+	// Project pages are optional markdown files named after the project
+	for i := range projects.Projects {
+		p := &projects.Projects[i]
+		body, err := os.ReadFile(filepath.Join("data", "projects", p.ID+".md"))
+		if err != nil && !os.IsNotExist(err) {
+			panic("Failed to load page for project " + p.ID + ": " + err.Error())
+		}
+		p.Body = string(body)
+	}
 
 	return &projects
 }
@@ -1,76 +1,5 @@
 package generation
 
-// Palette defines the tiles available for a biome
-type Palette struct {
-	// Terrain
-	Grass     string
-	Sand      string
-	Water     string
-	DeepWater string
-	Snow      string
-	Mountain  string
-	Peak      string
-
-	// Vegetation
-	Tree     string
-	PineTree string
-	Bush     string
-
-	// Structures
-	Building      string
-	WhiteBuilding string
-	WoodWall      string
-	Door          string
-	Pillar        string
-
-	// Infrastructure
-	Path        string
-	Cobblestone string
-	Dock        string
-	Bridge      string
-
-	// Special
-	Star   string
-	Marker string
-	Empty  string
-
-	// Additional details
-	Window    string
-	WoodFloor string
-	Chimney   string
-}
-
-// DefaultPalette returns the standard tile palette
-func DefaultPalette() *Palette {
-	return &Palette{
-		Grass:         "^",
-		Sand:          ".",
-		Water:         "~",
-		DeepWater:     "≈",
-		Snow:          "s",
-		Mountain:      "M",
-		Peak:          "A",
-		Tree:          "T",
-		PineTree:      "t",
-		Bush:          ";",
-		Building:      "#",
-		WhiteBuilding: "B",
-		WoodWall:      "W",
-		Door:          "D",
-		Pillar:        "|",
-		Path:          "+",
-		Cobblestone:   "o",
-		Dock:          "=",
-		Bridge:        "n",
-		Star:          "*",
-		Marker:        "@",
-		Empty:         " ",
-		Window:        "%",
-		WoodFloor:     "░",
-		Chimney:       "H",
-	}
-}
-
 // BiomeType identifies the type of terrain
 type BiomeType string
 
@@ -101,94 +30,111 @@ type Biome struct {
 	TreeDensity float64
 	BushDensity float64
 
+	// Path network - which connections become paths and what they're made of
+	Routing  RoutingMode
+	PathTile string
+
 	// Edge behavior - which edges have water/mountains/etc
 	Shorelines []Direction // Edges that have water
 	Mountains  []Direction // Edges that have mountains
 }
 
-// GetBiome returns the biome configuration for a type
-func GetBiome(t BiomeType) *Biome {
+// GetBiome returns the biome configuration for a type, drawing its tiles from
+// the given palette
+func GetBiome(t BiomeType, p *Palette) *Biome {
 	switch t {
 	case BiomeGrassland:
 		return &Biome{
 			Type:              BiomeGrassland,
-			BaseTile:          "^",
+			BaseTile:          p.Grass,
 			BaseWalkable:      true,
 			AllowedStructures: []string{"building", "cabin", "shrine"},
 			AllowedTerrain:    []string{"grove", "clearing"},
 			AllowedInfra:      []string{"plaza", "bridge"},
-			TreeType:          "T",
+			TreeType:          p.Tree,
 			TreeDensity:       0.03,
 			BushDensity:       0.01,
+			Routing:           RoutingMerged,
+			PathTile:          p.Path,
 		}
 
 	case BiomeMountain:
 		return &Biome{
 			Type:              BiomeMountain,
-			BaseTile:          "^",
+			BaseTile:          p.Grass,
 			BaseWalkable:      true,
 			AllowedStructures: []string{"cabin", "tower", "shrine"},
 			AllowedTerrain:    []string{"mountain_range", "clearing"},
 			AllowedInfra:      []string{"bridge"},
-			TreeType:          "t",
+			TreeType:          p.PineTree,
 			TreeDensity:       0.05,
 			BushDensity:       0.0,
+			Routing:           RoutingMST,
+			PathTile:          p.Path,
 		}
 
 	case BiomeCoastal:
 		return &Biome{
 			Type:              BiomeCoastal,
-			BaseTile:          "^",
+			BaseTile:          p.Grass,
 			BaseWalkable:      true,
 			AllowedStructures: []string{"building", "cabin"},
 			AllowedTerrain:    []string{"shoreline", "clearing"},
 			AllowedInfra:      []string{"plaza", "dock", "bridge"},
-			TreeType:          "T",
+			TreeType:          p.Tree,
 			TreeDensity:       0.02,
 			BushDensity:       0.02,
+			Routing:           RoutingMST,
+			PathTile:          p.Path,
 		}
 
 	case BiomeForest:
 		return &Biome{
 			Type:              BiomeForest,
-			BaseTile:          "^",
+			BaseTile:          p.Grass,
 			BaseWalkable:      true,
 			AllowedStructures: []string{"cabin", "shrine"},
 			AllowedTerrain:    []string{"grove", "clearing"},
 			AllowedInfra:      []string{"bridge"},
-			TreeType:          "T",
+			TreeType:          p.Tree,
 			TreeDensity:       0.15,
 			BushDensity:       0.05,
+			Routing:           RoutingMerged,
+			PathTile:          p.Dirt,
 		}
 
 	case BiomeUrban:
 		return &Biome{
 			Type:              BiomeUrban,
-			BaseTile:          "^",
+			BaseTile:          p.Grass,
 			BaseWalkable:      true,
 			AllowedStructures: []string{"building", "tower", "courtyard"},
 			AllowedTerrain:    []string{"clearing"},
 			AllowedInfra:      []string{"plaza"},
-			TreeType:          "T",
+			TreeType:          p.Tree,
 			TreeDensity:       0.01,
 			BushDensity:       0.02,
+			Routing:           RoutingLoops,
+			PathTile:          p.Cobblestone,
 		}
 
 	case BiomeCastle:
 		return &Biome{
 			Type:              BiomeCastle,
-			BaseTile:          "^",
+			BaseTile:          p.Grass,
 			BaseWalkable:      true,
 			AllowedStructures: []string{"building", "tower", "courtyard", "shrine"},
 			AllowedTerrain:    []string{"clearing"},
 			AllowedInfra:      []string{"plaza", "bridge"},
-			TreeType:          "T",
+			TreeType:          p.Tree,
 			TreeDensity:       0.02,
 			BushDensity:       0.01,
+			Routing:           RoutingLoops,
+			PathTile:          p.Cobblestone,
 		}
 
 	default:
-		return GetBiome(BiomeGrassland)
+		return GetBiome(BiomeGrassland, p)
 	}
 }
 
@@ -197,17 +143,112 @@ type ChunkConfig struct {
 	// Identity
 	ChunkX, ChunkY int
 	Seed           uint64
+	Size           int // Tiles per side; 0 means DefaultChunkSize
 
 	// Terrain
 	Biome      BiomeType
 	Shorelines []Direction // Which edges have water
+	Mountains  []Direction // Which edges have mountain ridges (mountain biome defaults to North)
+	Rivers     []RiverConfig
+	Lakes      []LakeConfig
 
 	// Connectivity - which edges connect to other chunks
 	Connections   []Direction
-	SignpostHints map[Direction]string // Hints for signposts at each exit
+	SignpostHints map[Direction]string      // Hints for signposts at each exit
+	Destinations  map[Direction]Destination // What signposts at each exit say; see WorldAtlas
 
 	// Projects to place in this chunk
 	Projects []ProjectPlacement
+
+	// Hand-placed zones: lore, landmarks and teleporters
+	Zones []ZoneConfig
+
+	// Appearance - the palette theme ("" for the default) and any glyphs
+	// this chunk overrides, by tile type
+	Palette string
+	Glyphs  map[string]string
+
+	// Layers adds ground, overlay, collision, owner and elevation layers to
+	// the output alongside the tiles
+	Layers bool
+
+	// Legacy draws with the RNG and trig used before the switch to xoshiro
+	// and exact trig: one LCG sequence shared by every phase, Taylor-series
+	// cos/sin. The generator has changed in other ways since, so this doesn't
+	// bring back worlds generated back then.
+	Legacy bool
+}
+
+// ScaledTo returns a copy of a config laid out for a DefaultChunkSize chunk,
+// resized to the given size. Absolute positions (river offsets, lakes,
+// pinned projects and hand-placed zones) are scaled to match, and structures
+// shrink with smaller chunks; everything else is already relative.
+func (c ChunkConfig) ScaledTo(size int) ChunkConfig {
+	scale := func(n int) int { return n * size / DefaultChunkSize }
+
+	c.Size = size
+
+	rivers := make([]RiverConfig, len(c.Rivers))
+	for i, r := range c.Rivers {
+		r.EntryOffset, r.ExitOffset = scale(r.EntryOffset), scale(r.ExitOffset)
+		rivers[i] = r
+	}
+	c.Rivers = rivers
+
+	lakes := make([]LakeConfig, len(c.Lakes))
+	for i, l := range c.Lakes {
+		lakes[i] = LakeConfig{Center: Point{scale(l.Center.X), scale(l.Center.Y)}, Radius: max(scale(l.Radius), 1)}
+	}
+	c.Lakes = lakes
+
+	projects := make([]ProjectPlacement, len(c.Projects))
+	for i, p := range c.Projects {
+		if p.Pinned != nil {
+			p.Pinned = &Point{scale(p.Pinned.X), scale(p.Pinned.Y)}
+		}
+		// Smaller chunks get smaller structures so crowded layouts still fit
+		// (the generator shrinks their footprints, margins and port
+		// approaches to match); larger ones just get more room around them
+		if size < DefaultChunkSize {
+			p.Size = max(p.Size*size/DefaultChunkSize, 1)
+		}
+		projects[i] = p
+	}
+	c.Projects = projects
+
+	// A masked zone keeps its shape, so only its corner moves
+	zones := make([]ZoneConfig, len(c.Zones))
+	for i, z := range c.Zones {
+		if z.Mask == nil {
+			z.Bounds = Bounds{scale(z.Bounds.MinX), scale(z.Bounds.MinY), scale(z.Bounds.MaxX), scale(z.Bounds.MaxY)}
+		} else {
+			w, h := z.Bounds.MaxX-z.Bounds.MinX, z.Bounds.MaxY-z.Bounds.MinY
+			z.Bounds.MinX, z.Bounds.MinY = scale(z.Bounds.MinX), scale(z.Bounds.MinY)
+			z.Bounds.MaxX, z.Bounds.MaxY = z.Bounds.MinX+w, z.Bounds.MinY+h
+		}
+		zones[i] = z
+	}
+	c.Zones = zones
+
+	return c
+}
+
+// RiverConfig describes a river flowing through a chunk. Offsets are measured
+// along the edge, so a river leaving East at offset 30 continues in the
+// neighbouring chunk by entering West at offset 30 with the same width.
+type RiverConfig struct {
+	Entry       Direction // Edge the river flows in from
+	EntryOffset int
+	Exit        Direction // Edge the river flows out of
+	ExitOffset  int
+	Width       int // Tiles of water across (odd widths look best)
+	Meander     int // How far the course may wander from a straight run
+}
+
+// LakeConfig places a lake in the chunk interior
+type LakeConfig struct {
+	Center Point
+	Radius int
 }
 
 // ProjectPlacement defines where a project should be placed
@@ -217,20 +258,60 @@ type ProjectPlacement struct {
 	Description string
 	Structure   string // "building", "tower", "shrine", "courtyard"
 	Size        int    // Relative size (1-3)
+
+	// Placement hints (optional)
+	Quadrant  string      // Preferred quadrant: "nw", "ne", "sw" or "se"
+	Pinned    *Point      // Exact center position; generation fails if it doesn't fit
+	Entrances []Direction // Door sides; chosen from the structure's graph neighbours if empty
+
+	// Artifacts get a display pedestal each inside the structure. Defaults
+	// to a single pedestal for the project itself.
+	Artifacts []string
+}
+
+// ZoneConfig is a zone placed by hand rather than derived from a structure
+type ZoneConfig struct {
+	Name        string
+	Description string
+	Type        string            // ZoneTypeLore, ZoneTypeLandmark or ZoneTypeTeleporter
+	Bounds      Bounds            // For a DefaultChunkSize chunk when scaled
+	Mask        []string          // Optional shape over Bounds: one row per tile row, '#' in the zone
+	Priority    int               // Higher wins where zones overlap
+	Metadata    map[string]string // Teleporters need "to": "x,y" in world tiles
 }
 
 // ChunkDefinition is the output - matches the JSON format
 type ChunkDefinition struct {
-	Tiles [][]string `json:"tiles"`
-	Zones []ZoneDef  `json:"zones"`
+	Tiles     [][]string    `json:"tiles"`
+	Zones     []ZoneDef     `json:"zones"`
+	Interiors []InteriorDef `json:"interiors,omitempty"`
+	Layers    *LayersDef    `json:"layers,omitempty"`
+
+	// The seed and RNG that drew the chunk, so it can be drawn again
+	Seed   uint64 `json:"seed"`
+	Legacy bool   `json:"legacy,omitempty"`
 }
 
 // ZoneDef matches the JSON zone format
 type ZoneDef struct {
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Bounds      BoundsDef `json:"bounds"`
-	ProjectID   string    `json:"project_id,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Bounds      BoundsDef         `json:"bounds"`
+	ProjectID   string            `json:"project_id,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Interior    string            `json:"interior,omitempty"`
+	Mask        []string          `json:"mask,omitempty"`
+	Priority    int               `json:"priority,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// InteriorDef is the walkable inside of a structure, entered through its doors
+type InteriorDef struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name"`
+	Tiles [][]string `json:"tiles"`
+	Zones []ZoneDef  `json:"zones"`
+	Spawn [2]int     `json:"spawn"` // Where the player appears on entering
 }
 
 // BoundsDef matches the JSON bounds format
@@ -2,85 +2,207 @@ package generation
 
 import (
 	"fmt"
+	"math"
+	"strings"
 )
 
-const ChunkSize = 50
+// DefaultChunkSize is the side length of a chunk when the config doesn't
+// set one. Fixed layout distances are written for this size and scaled.
+const DefaultChunkSize = 50
+
+// minChunkSize is the smallest chunk the layouts still fit in
+const minChunkSize = 24
+
+// maxBridgeSpan is the widest stretch of water a bridge will cross
+const maxBridgeSpan = 8
 
 // ChunkGenerator generates chunk data from configuration
 type ChunkGenerator struct {
 	config  *ChunkConfig
+	size    int // Tiles per side
 	grid    *Grid
 	graph   *Graph
 	palette *Palette
 	biome   *Biome
 	rng     *RNG
+	streams map[string]*RNG // Per-phase streams split from rng
 
 	components      []Component // Structural components (rendered before paths)
 	terrainFeatures []Component // Terrain features (rendered after paths)
 	zones           []*Zone
+	interiors       []*Interior
+	componentIDs    map[Component]string // Owner layer IDs, assigned as drawn
+	componentKinds  map[string]int       // Components of each kind named so far
+
+	trace *Trace // Only recorded when enabled
+
+	paletteErr error // Why the configured palette couldn't be used
 }
 
 // NewChunkGenerator creates a generator for the given config
 func NewChunkGenerator(config *ChunkConfig) *ChunkGenerator {
+	// A bad palette is reported by Generate; draw with the default meanwhile
+	palette, err := config.ResolvePalette()
+	if err != nil {
+		palette = DefaultPalette()
+	}
+
 	return &ChunkGenerator{
 		config:          config,
-		palette:         DefaultPalette(),
-		biome:           GetBiome(config.Biome),
-		rng:             NewRNG(config.Seed),
+		size:            chunkSize(config),
+		palette:         palette,
+		paletteErr:      err,
+		biome:           GetBiome(config.Biome, palette),
+		rng:             newChunkRNG(config),
+		streams:         make(map[string]*RNG),
 		components:      make([]Component, 0),
 		terrainFeatures: make([]Component, 0),
 		zones:           make([]*Zone, 0),
+		interiors:       make([]*Interior, 0),
+		componentIDs:    make(map[Component]string),
+		componentKinds:  make(map[string]int),
+	}
+}
+
+// chunkSize returns the side length configured for a chunk
+func chunkSize(config *ChunkConfig) int {
+	if config.Size > 0 {
+		return config.Size
+	}
+	return DefaultChunkSize
+}
+
+// rel converts a distance laid out for a DefaultChunkSize chunk to this
+// chunk's size
+func (cg *ChunkGenerator) rel(n int) int {
+	return n * cg.size / DefaultChunkSize
+}
+
+// relBounds converts bounds laid out for a DefaultChunkSize chunk to this
+// chunk's size
+func (cg *ChunkGenerator) relBounds(b Bounds) Bounds {
+	return Bounds{cg.rel(b.MinX), cg.rel(b.MinY), cg.rel(b.MaxX), cg.rel(b.MaxY)}
+}
+
+// ridgeDepth is the typical depth of a mountain ridge from its outer edge
+func (cg *ChunkGenerator) ridgeDepth() int {
+	return cg.size / 5
+}
+
+// shoreDepths is how many tiles of water and then sand a shoreline runs in
+// from its edge, kept to at least two of water and one of sand
+func (cg *ChunkGenerator) shoreDepths() (water, sand int) {
+	return max(cg.rel(3), 2), max(cg.rel(2), 1)
+}
+
+// signpostInset is how far in from the edge each exit's signpost stands,
+// kept to at least 3 so the signpost's zone stays off the edge row
+func (cg *ChunkGenerator) signpostInset() int {
+	return max(cg.rel(4), 3)
+}
+
+// plazaRadius is the radius of the hub plaza, kept to at least 2 so the
+// hub stays recognisable in small chunks
+func (cg *ChunkGenerator) plazaRadius() int {
+	return max(cg.rel(3), 2)
+}
+
+// newChunkRNG creates the generator's root RNG
+func newChunkRNG(config *ChunkConfig) *RNG {
+	if config.Legacy {
+		return NewLegacyRNG(config.Seed)
+	}
+	return NewRNG(config.Seed)
+}
+
+// stream returns the RNG stream for a phase, so that changing what one phase
+// draws doesn't reshuffle the phases after it
+func (cg *ChunkGenerator) stream(name string) *RNG {
+	if r, ok := cg.streams[name]; ok {
+		return r
 	}
+	r := cg.rng.Split(name)
+	cg.streams[name] = r
+	return r
 }
 
 // Generate produces the chunk definition
 func (cg *ChunkGenerator) Generate() (*ChunkDefinition, error) {
+	if cg.size < minChunkSize {
+		return nil, fmt.Errorf("chunk size %d is below the minimum of %d", cg.size, minChunkSize)
+	}
+	if cg.paletteErr != nil {
+		return nil, cg.paletteErr
+	}
+	if err := cg.checkZones(); err != nil {
+		return nil, err
+	}
+
 	// 1. Initialize grid with base terrain
 	cg.initGrid()
+	cg.tracePhase("init grid")
 
 	// 2. Build the connectivity graph
 	cg.buildGraph()
+	cg.tracePhase("build graph")
 
 	// 3. Place edge terrain (shorelines, mountains)
 	cg.placeTerrain()
+	cg.tracePhase("place terrain")
 
 	// 4. Place project structures
 	if err := cg.placeProjects(); err != nil {
-		return nil, fmt.Errorf("placing projects: %w", err)
+		return nil, cg.traceFailure("place projects", fmt.Errorf("placing projects: %w", err))
 	}
+	cg.tracePhase("place projects")
 
 	// 5. Create central hub if we have multiple connections
 	cg.placeHub()
+	cg.tracePhase("place hub")
 
-	// 6. Place signposts at exits
+	// 6. Place signposts at exits, and any hand-placed zones
 	cg.placeSignposts()
+	cg.placeZones()
+	cg.tracePhase("place signposts")
 
 	// 7. Render structural components (buildings, terrain edges)
 	cg.renderComponents()
+	cg.tracePhase("render components")
 
 	// 8. Route paths between graph nodes
 	if err := cg.routePaths(); err != nil {
-		return nil, fmt.Errorf("routing paths: %w", err)
+		return nil, cg.traceFailure("route paths", fmt.Errorf("routing paths: %w", err))
 	}
+	cg.tracePhase("route paths")
 
 	// 9. Add terrain features AFTER paths (so they don't block routes)
 	cg.placeTerrainFeatures()
 	cg.renderTerrainFeatures()
+	cg.tracePhase("terrain features")
 
 	// 10. Add decoration (trees, bushes)
 	cg.addDecoration()
+	cg.tracePhase("decoration")
 
 	// 11. Validate accessibility
 	if err := cg.validate(); err != nil {
-		return nil, fmt.Errorf("validation failed: %w", err)
+		return nil, cg.traceFailure("validate", fmt.Errorf("validation failed: %w", err))
 	}
+	cg.tracePhase("validate")
 
 	// 12. Build output
-	return cg.buildOutput(), nil
+	output := cg.buildOutput()
+	cg.tracePhase("build output")
+	cg.traceGraph()
+
+	return output, nil
 }
 
 func (cg *ChunkGenerator) initGrid() {
-	cg.grid = NewGrid(ChunkSize, ChunkSize, cg.biome.BaseTile, cg.biome.BaseWalkable)
+	cg.grid = NewGrid(cg.size, cg.size, cg.biome.BaseTile, cg.biome.BaseWalkable)
+	if cg.config.Layers {
+		cg.grid.TrackLayers(cg.palette)
+	}
 }
 
 func (cg *ChunkGenerator) buildGraph() {
@@ -94,19 +216,7 @@ func (cg *ChunkGenerator) buildGraph() {
 }
 
 func (cg *ChunkGenerator) createEdgePort(dir Direction) *Node {
-	var pos Point
-	mid := ChunkSize / 2
-
-	switch dir {
-	case North:
-		pos = Point{mid, 0}
-	case South:
-		pos = Point{mid, ChunkSize - 1}
-	case East:
-		pos = Point{ChunkSize - 1, mid}
-	case West:
-		pos = Point{0, mid}
-	}
+	pos := cg.edgePoint(dir, cg.size/2)
 
 	return &Node{
 		ID:       fmt.Sprintf("port_%d", dir),
@@ -117,27 +227,92 @@ func (cg *ChunkGenerator) createEdgePort(dir Direction) *Node {
 	}
 }
 
+// edgePoint returns the border tile on the given side, offset tiles along it
+func (cg *ChunkGenerator) edgePoint(side Direction, offset int) Point {
+	switch side {
+	case North:
+		return Point{offset, 0}
+	case South:
+		return Point{offset, cg.size - 1}
+	case East:
+		return Point{cg.size - 1, offset}
+	case West:
+		return Point{0, offset}
+	}
+	return Point{offset, 0}
+}
+
 func (cg *ChunkGenerator) placeTerrain() {
 	// Place shorelines
 	for _, dir := range cg.config.Shorelines {
-		shore := NewShoreline(dir, 3, 2, ChunkSize)
+		water, sand := cg.shoreDepths()
+		shore := NewShoreline(dir, water, sand, cg.size)
 		cg.components = append(cg.components, shore)
 	}
 
-	// Mountain biome gets mountains along the top/northwest
-	if cg.config.Biome == BiomeMountain {
-		// Place mountains in upper-left, leaving passes for connections
-		passes := make([]Point, 0)
-		// Add a pass in the middle for traversal
-		passes = append(passes, Point{15, 10})
-
-		mtns := NewMountainRange(
-			Bounds{3, 3, 25, 12},
-			passes,
-			2,
+	// Rivers and lakes are laid down before structures so buildings sit on top
+	for _, rc := range cg.config.Rivers {
+		width := rc.Width
+		if width == 0 {
+			width = 3
+		}
+		river := NewRiver(
+			cg.edgePoint(rc.Entry, rc.EntryOffset),
+			cg.edgePoint(rc.Exit, rc.ExitOffset),
+			rc.Entry, rc.Exit,
+			width, rc.Meander, cg.size, cg.stream("terrain"),
 		)
-		cg.components = append(cg.components, mtns)
+		cg.components = append(cg.components, river)
+	}
+	for _, lc := range cg.config.Lakes {
+		cg.components = append(cg.components, NewLake(lc.Center, lc.Radius, cg.stream("terrain")))
+	}
+
+	// Mountain ridges back onto their edges, with a pass wherever a
+	// connection has to get through
+	for _, side := range cg.mountainSides() {
+		var passes []int
+		if cg.hasConnection(side) {
+			passes = append(passes, cg.size/2)
+		}
+
+		// Run the ridge between any shorelines on the adjoining edges
+		from, to := 0, cg.size-1
+		if side == North || side == South {
+			from, to = cg.edgeInset(West), cg.size-1-cg.edgeInset(East)
+		} else {
+			from, to = cg.edgeInset(North), cg.size-1-cg.edgeInset(South)
+		}
+
+		ridge := NewMountainRidge(side, from, to, cg.edgeInset(side), cg.ridgeDepth(), passes, cg.size, cg.stream("terrain"))
+		cg.components = append(cg.components, ridge)
+	}
+}
+
+// mountainSides returns the edges that carry a mountain ridge
+func (cg *ChunkGenerator) mountainSides() []Direction {
+	if len(cg.config.Mountains) > 0 {
+		return cg.config.Mountains
+	}
+	if cg.config.Biome == BiomeMountain {
+		// Mountains take up the top portion by default
+		return []Direction{North}
+	}
+	return nil
+}
+
+// hasConnection reports whether the chunk connects to a neighbour on side
+func (cg *ChunkGenerator) hasConnection(side Direction) bool {
+	return hasDirection(cg.config.Connections, side)
+}
+
+// edgeInset returns how many tiles along an edge are taken by shoreline
+func (cg *ChunkGenerator) edgeInset(side Direction) int {
+	if hasDirection(cg.config.Shorelines, side) {
+		water, sand := cg.shoreDepths()
+		return water + sand
 	}
+	return 0
 }
 
 func (cg *ChunkGenerator) placeProjects() error {
@@ -145,64 +320,68 @@ func (cg *ChunkGenerator) placeProjects() error {
 		return nil
 	}
 
-	// Calculate placement positions based on number of projects
-	positions := cg.calculateProjectPositions(len(cg.config.Projects))
+	// The classic layouts give each project a preferred position; the solver
+	// moves it as little as possible to satisfy the placement constraints
+	targets := cg.calculateProjectPositions(len(cg.config.Projects))
+	solver := newPlacementSolver(cg)
 
+	// Pinned projects claim their space before anything is searched for
+	order := make([]int, 0, len(cg.config.Projects))
 	for i, proj := range cg.config.Projects {
-		pos := positions[i]
+		if proj.Pinned != nil {
+			order = append(order, i)
+		}
+	}
+	for i, proj := range cg.config.Projects {
+		if proj.Pinned == nil {
+			order = append(order, i)
+		}
+	}
 
+	comps := make([]Component, len(cg.config.Projects))
+	zones := make([]*Zone, len(cg.config.Projects))
+	for _, i := range order {
+		proj := cg.config.Projects[i]
 		zone := &Zone{
 			Name:        proj.Name,
 			Description: proj.Description,
 			ProjectID:   proj.ProjectID,
+			Type:        ZoneTypeProject,
 		}
 
-		var comp Component
-
-		// Create structure based on type
-		switch proj.Structure {
-		case "tower":
-			radius := 3 + proj.Size
-			entranceDir := cg.findBestEntrance(pos, radius)
-			comp = NewTower(pos, radius, entranceDir, zone)
-
-		case "shrine":
-			size := proj.Size
-			comp = NewShrine(pos, size, zone)
-
-		case "courtyard":
-			size := 4 + proj.Size*2
-			bounds := Bounds{pos.X - size, pos.Y - size, pos.X + size, pos.Y + size}
-			entrances := []Direction{South} // Default entrance
-			comp = NewCourtyard(bounds, "stone", entrances, zone)
-
-		case "cabin":
-			size := 3 + proj.Size
-			bounds := Bounds{pos.X - size, pos.Y - size/2, pos.X + size, pos.Y + size/2}
-			entranceDir := cg.findBestEntrance(pos, size)
-			comp = NewCabin(bounds, entranceDir, zone)
-
-		default: // "building"
-			size := 3 + proj.Size
-			bounds := Bounds{pos.X - size, pos.Y - size/2, pos.X + size, pos.Y + size/2}
-			entranceDir := cg.findBestEntrance(pos, size)
-			comp = NewBuilding(bounds, "stone", entranceDir, zone)
+		comp, err := solver.place(proj, targets[i], zone)
+		if err != nil {
+			return err
 		}
 
 		// Update zone bounds from component
 		zone.Bounds = comp.GetBounds()
+		comps[i] = comp
+		zones[i] = zone
+	}
 
+	// Keep components and zones in config order so output is stable
+	for i, proj := range cg.config.Projects {
+		comp := comps[i]
 		cg.components = append(cg.components, comp)
-		cg.zones = append(cg.zones, zone)
+
+		// Enterable structures get an interior, reached through each door.
+		// Door zones go first so they win over the structure's own zone.
+		if hasInterior(proj.Structure) {
+			if err := cg.buildInterior(proj, comp); err != nil {
+				return err
+			}
+		}
+		cg.zones = append(cg.zones, zones[i])
 
 		// Add to graph
 		node := &Node{
 			ID:       fmt.Sprintf("project_%s", proj.ProjectID),
 			Type:     NodeComponent,
-			Position: pos,
+			Position: comp.GetBounds().Center(),
 			Anchors:  comp.GetAnchors(),
 			Bounds:   comp.GetBounds(),
-			Zone:     zone,
+			Zone:     zones[i],
 		}
 		cg.graph.AddNode(node)
 	}
@@ -210,29 +389,111 @@ func (cg *ChunkGenerator) placeProjects() error {
 	return nil
 }
 
+// buildInterior lays out and validates the inside of a project structure and
+// adds a zone on each of its doors leading in
+func (cg *ChunkGenerator) buildInterior(proj ProjectPlacement, comp Component) error {
+	// Interiors get their own stream so they never disturb the overworld
+	rng := NewRNG(cg.config.Seed).Split("interior:" + proj.ProjectID)
+	if cg.config.Legacy {
+		rng = NewLegacyRNG(cg.config.Seed ^ hashName(proj.ProjectID))
+	}
+	interior, err := NewInterior(proj, cg.palette, rng)
+	if err != nil {
+		return err
+	}
+	if err := interior.Validate(); err != nil {
+		return err
+	}
+	cg.interiors = append(cg.interiors, interior)
+
+	for _, anchor := range comp.GetAnchors() {
+		// Anchors sit one tile outside the door, facing it
+		dx, dy := anchor.Direction.Delta()
+		door := anchor.Position.Add(dx, dy)
+		cg.zones = append(cg.zones, &Zone{
+			Name:        proj.Name,
+			Description: "The door stands open. Step inside.",
+			Bounds:      Bounds{door.X, door.Y, door.X, door.Y},
+			ProjectID:   proj.ProjectID,
+			Type:        ZoneTypeInterior,
+			Interior:    interior.ID,
+			Priority:    zonePriorityDoor,
+		})
+	}
+
+	return nil
+}
+
+// buildStructure creates the component for a project centred on pos. With
+// no entrances given, a single door faces the chunk center.
+func (cg *ChunkGenerator) buildStructure(proj ProjectPlacement, pos Point, entrances []Direction, zone *Zone) Component {
+	if len(entrances) == 0 {
+		entrances = []Direction{cg.findBestEntrance(pos)}
+	}
+
+	// Footprints are laid out for a DefaultChunkSize chunk and shrink with
+	// smaller ones, down to the smallest that still has a room inside
+	base := max(cg.rel(3), 2)
+
+	switch proj.Structure {
+	case "tower":
+		radius := base + proj.Size
+		return NewTower(pos, radius, entrances, zone)
+
+	case "shrine":
+		size := proj.Size
+		return NewShrine(pos, size, zone)
+
+	case "courtyard":
+		size := base + 1 + proj.Size*2
+		bounds := Bounds{pos.X - size, pos.Y - size, pos.X + size, pos.Y + size}
+		return NewCourtyard(bounds, "stone", entrances, zone)
+
+	case "cabin":
+		size := base + proj.Size
+		bounds := Bounds{pos.X - size, pos.Y - size/2, pos.X + size, pos.Y + size/2}
+		return NewCabin(bounds, entrances, zone)
+
+	default: // "building"
+		size := base + proj.Size
+		bounds := Bounds{pos.X - size, pos.Y - size/2, pos.X + size, pos.Y + size/2}
+		return NewBuilding(bounds, "stone", entrances, zone)
+	}
+}
+
 func (cg *ChunkGenerator) calculateProjectPositions(count int) []Point {
 	positions := make([]Point, count)
 
 	// Calculate safe bounds (avoid shorelines)
-	minX, minY := 10, 10
-	maxX, maxY := ChunkSize-10, ChunkSize-10
+	minX, minY := cg.rel(10), cg.rel(10)
+	maxX, maxY := cg.size-cg.rel(10), cg.size-cg.rel(10)
 
 	for _, dir := range cg.config.Shorelines {
 		switch dir {
 		case North:
-			minY = 15
+			minY = cg.rel(15)
 		case South:
-			maxY = ChunkSize - 15
+			maxY = cg.size - cg.rel(15)
 		case East:
-			maxX = ChunkSize - 15
+			maxX = cg.size - cg.rel(15)
 		case West:
-			minX = 15
+			minX = cg.rel(15)
 		}
 	}
 
-	// Also avoid mountain areas in mountain biome
-	if cg.config.Biome == BiomeMountain {
-		minY = max(minY, 20) // Mountains take up top portion
+	// Also keep clear of mountain ridges, including their jagged inner edge
+	for _, side := range cg.mountainSides() {
+		reach := cg.edgeInset(side) + cg.ridgeDepth() + 2 + 3
+		switch side {
+		case North:
+			minY = max(minY, reach)
+		case South:
+			maxY = min(maxY, cg.size-1-reach)
+		case East:
+			maxX = min(maxX, cg.size-1-reach)
+		case West:
+			minX = max(minX, reach)
+		}
 	}
 
 	// Calculate center of safe area
@@ -276,17 +537,22 @@ func (cg *ChunkGenerator) calculateProjectPositions(count int) []Point {
 	// For 5+ projects, distribute in safe area
 	radius := min(safeWidth, safeHeight) / 3
 	for i := 0; i < count; i++ {
-		angle := float64(i) * (6.28318 / float64(count))
-		dx := int(float64(radius) * cos(angle))
-		dy := int(float64(radius) * sin(angle))
+		angle := float64(i) * (2 * math.Pi / float64(count))
+		dx := int(float64(radius) * math.Cos(angle))
+		dy := int(float64(radius) * math.Sin(angle))
+		if cg.config.Legacy {
+			angle = float64(i) * (6.28318 / float64(count))
+			dx = int(float64(radius) * legacyCos(angle))
+			dy = int(float64(radius) * legacySin(angle))
+		}
 		positions[i] = Point{centerX + dx, centerY + dy}
 	}
 
 	return positions
 }
 
-func (cg *ChunkGenerator) findBestEntrance(pos Point, size int) Direction {
-	center := Point{ChunkSize / 2, ChunkSize / 2}
+func (cg *ChunkGenerator) findBestEntrance(pos Point) Direction {
+	center := Point{cg.size / 2, cg.size / 2}
 
 	// Entrance should face toward center of chunk
 	dx := center.X - pos.X
@@ -307,7 +573,7 @@ func (cg *ChunkGenerator) findBestEntrance(pos Point, size int) Direction {
 func (cg *ChunkGenerator) placeHub() {
 	// If we have multiple connections or projects, add a central hub
 	if len(cg.config.Connections) > 1 || len(cg.config.Projects) > 0 {
-		center := Point{ChunkSize / 2, ChunkSize / 2}
+		center := Point{cg.size / 2, cg.size / 2}
 
 		// Check if a project component already occupies the center
 		var hubNodeID string
@@ -323,7 +589,7 @@ func (cg *ChunkGenerator) placeHub() {
 
 		// If center is free, create plaza and hub node
 		if hubNodeID == "" {
-			plaza := NewPlaza(center, 3, "square")
+			plaza := NewPlaza(center, cg.plazaRadius(), "square")
 			cg.components = append(cg.components, plaza)
 
 			hubNode := &Node{
@@ -359,87 +625,142 @@ func (cg *ChunkGenerator) placeHub() {
 func (cg *ChunkGenerator) placeSignposts() {
 	// Add signposts ON the path near edge connections
 	for _, dir := range cg.config.Connections {
-		hint := cg.config.SignpostHints[dir]
-		if hint == "" {
-			hint = "A path leads onward..."
+		dest, ok := cg.config.Destinations[dir]
+		if !ok {
+			dest.Lines = []string{cg.config.SignpostHints[dir]}
+			if dest.Lines[0] == "" {
+				dest.Lines[0] = "A path leads onward..."
+			}
 		}
 
 		// Position signpost ON the path, a few tiles in from edge
 		var pos Point
-		offset := 4
-		mid := ChunkSize / 2
+		offset := cg.signpostInset()
+		mid := cg.size / 2
 
 		switch dir {
 		case North:
 			pos = Point{mid, offset} // On the north path
 		case South:
-			pos = Point{mid, ChunkSize - 1 - offset} // On the south path
+			pos = Point{mid, cg.size - 1 - offset} // On the south path
 		case East:
-			pos = Point{ChunkSize - 1 - offset, mid} // On the east path
+			pos = Point{cg.size - 1 - offset, mid} // On the east path
 		case West:
 			pos = Point{offset, mid} // On the west path
 		}
 
-		signpost := NewSignpost(pos, dir, "", hint)
+		signpost := NewSignpost(pos, dir, dest.Name, strings.Join(dest.Lines, "\n"))
 		cg.components = append(cg.components, signpost)
 		cg.zones = append(cg.zones, signpost.GetZone())
 	}
 }
 
+// checkZones makes sure the hand-placed zones are usable before anything is
+// generated
+func (cg *ChunkGenerator) checkZones() error {
+	for _, z := range cg.config.Zones {
+		switch z.Type {
+		case ZoneTypeLore, ZoneTypeLandmark:
+		case ZoneTypeTeleporter:
+			if _, _, ok := strings.Cut(z.Metadata["to"], ","); !ok {
+				return fmt.Errorf("teleporter zone %q needs a \"to\" of \"x,y\" in its metadata", z.Name)
+			}
+		default:
+			return fmt.Errorf("zone %q has type %q; hand-placed zones must be lore, landmark or teleporter", z.Name, z.Type)
+		}
+
+		b := z.Bounds
+		if b.MinX < 0 || b.MinY < 0 || b.MaxX >= cg.size || b.MaxY >= cg.size || b.MinX > b.MaxX || b.MinY > b.MaxY {
+			return fmt.Errorf("zone %q bounds (%d,%d)-(%d,%d) are outside the chunk", z.Name, b.MinX, b.MinY, b.MaxX, b.MaxY)
+		}
+		if z.Mask != nil {
+			if len(z.Mask) != b.MaxY-b.MinY+1 {
+				return fmt.Errorf("zone %q mask has %d rows, its bounds %d", z.Name, len(z.Mask), b.MaxY-b.MinY+1)
+			}
+			for _, row := range z.Mask {
+				if len(row) != b.MaxX-b.MinX+1 {
+					return fmt.Errorf("zone %q mask row %q doesn't match its width of %d", z.Name, row, b.MaxX-b.MinX+1)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// placeZones adds the hand-placed zones from the config
+func (cg *ChunkGenerator) placeZones() {
+	for _, z := range cg.config.Zones {
+		cg.zones = append(cg.zones, &Zone{
+			Name:        z.Name,
+			Description: z.Description,
+			Bounds:      z.Bounds,
+			Type:        z.Type,
+			Mask:        z.Mask,
+			Priority:    z.Priority,
+			Metadata:    z.Metadata,
+		})
+	}
+}
+
 func (cg *ChunkGenerator) placeTerrainFeatures() {
+	rng := cg.stream("features")
+
 	// Add biome-specific terrain features in chunk interior
 	// These are placed AFTER paths are routed so they don't block connectivity
 
 	switch cg.config.Biome {
 	case BiomeGrassland:
 		// Add a pond in a corner
-		if cg.rng.Float64() < 0.5 {
-			pos := Point{10 + cg.rng.Intn(8), 38 + cg.rng.Intn(5)}
-			pond := NewPond(pos, 3)
+		if rng.Float64() < 0.5 {
+			pos := Point{cg.rel(10 + rng.Intn(8)), cg.rel(38 + rng.Intn(5))}
+			pond := NewPond(pos, max(cg.rel(3), 2))
 			cg.terrainFeatures = append(cg.terrainFeatures, pond)
 		}
 
 	case BiomeForest:
 		// Add dense grove areas in corners (away from paths)
-		grove1 := NewGrove(Bounds{5, 5, 12, 12}, 0.35, cg.palette.Tree, cg.rng)
-		grove2 := NewGrove(Bounds{38, 38, 45, 45}, 0.35, cg.palette.Tree, cg.rng)
+		grove1 := NewGrove(cg.relBounds(Bounds{5, 5, 12, 12}), 0.35, cg.palette.Tree, rng)
+		grove2 := NewGrove(cg.relBounds(Bounds{38, 38, 45, 45}), 0.35, cg.palette.Tree, rng)
 		cg.terrainFeatures = append(cg.terrainFeatures, grove1, grove2)
 
 	case BiomeCoastal:
 		// Add dock extending into water if we have east shoreline
 		for _, dir := range cg.config.Shorelines {
 			if dir == East {
-				dock := NewDock(Point{ChunkSize - 8, ChunkSize / 2}, East, 5, 3, nil)
+				// From a few tiles inland, across the sand to the water's edge
+				_, sand := cg.shoreDepths()
+				inland := max(cg.rel(3), 2)
+				dock := NewDock(Point{cg.size - cg.edgeInset(East) - inland, cg.size / 2}, East, inland+sand, 3, nil)
 				cg.terrainFeatures = append(cg.terrainFeatures, dock)
 			}
 		}
 
 	case BiomeUrban:
 		// Add a garden
-		garden := NewGarden(Bounds{8, 38, 15, 45}, cg.rng)
+		garden := NewGarden(cg.relBounds(Bounds{8, 38, 15, 45}), rng)
 		cg.terrainFeatures = append(cg.terrainFeatures, garden)
 
 	case BiomeCastle:
 		// Add ruins in a corner
-		ruins := NewRuins(Bounds{38, 5, 44, 10}, 0.4, cg.rng)
+		ruins := NewRuins(cg.relBounds(Bounds{38, 5, 44, 10}), 0.4, rng)
 		cg.terrainFeatures = append(cg.terrainFeatures, ruins)
 
 	case BiomeMountain:
 		// Add a small pine grove in the lower portion
-		grove := NewGrove(Bounds{35, 38, 42, 45}, 0.2, cg.palette.PineTree, cg.rng)
+		grove := NewGrove(cg.relBounds(Bounds{35, 38, 42, 45}), 0.2, cg.palette.PineTree, rng)
 		cg.terrainFeatures = append(cg.terrainFeatures, grove)
 	}
 }
 
 func (cg *ChunkGenerator) renderTerrainFeatures() {
 	for _, feat := range cg.terrainFeatures {
-		feat.Render(cg.grid, cg.palette)
+		cg.render(feat)
 	}
 }
 
 func (cg *ChunkGenerator) renderComponents() {
 	for _, comp := range cg.components {
-		comp.Render(cg.grid, cg.palette)
+		cg.render(comp)
 	}
 }
 
@@ -465,6 +786,13 @@ func (cg *ChunkGenerator) routePaths() error {
 		}
 	}
 
+	cg.traceAvoid(avoid)
+
+	cg.planRoutes()
+	if cg.biome.Routing == RoutingMerged {
+		return cg.routeMerged(avoid)
+	}
+
 	// Route each edge
 	for _, edge := range cg.graph.Edges {
 		fromNode := cg.graph.Nodes[edge.From]
@@ -473,13 +801,23 @@ func (cg *ChunkGenerator) routePaths() error {
 		// Find best anchors to connect
 		fromAnchor := cg.findClosestAnchor(fromNode, toNode.Position)
 		toAnchor := cg.findClosestAnchor(toNode, fromNode.Position)
+		edge.FromAnchor, edge.ToAnchor, edge.Routed = fromAnchor, toAnchor, true
 
 		// Find path
-		path := cg.grid.FindPathAvoid(fromAnchor, toAnchor, avoid)
-		if path == nil {
+		path := cg.grid.FindPathCost(fromAnchor, toAnchor, cg.pathOptions(avoid))
+		if path == nil && edge.Required {
 			// Try without avoidance for required edges
-			if edge.Required {
-				path = cg.grid.FindPath(fromAnchor, toAnchor, nil)
+			path = cg.grid.FindPathCost(fromAnchor, toAnchor, cg.pathOptions(nil))
+
+			// Still blocked - if water is in the way, bridge it and retry
+			for bridges := 0; path == nil && bridges < 3; bridges++ {
+				if !cg.bridgeWater(fromAnchor, toAnchor) {
+					break
+				}
+				path = cg.grid.FindPathCost(fromAnchor, toAnchor, cg.pathOptions(avoid))
+				if path == nil {
+					path = cg.grid.FindPathCost(fromAnchor, toAnchor, cg.pathOptions(nil))
+				}
 			}
 		}
 
@@ -489,16 +827,80 @@ func (cg *ChunkGenerator) routePaths() error {
 
 		if path != nil {
 			edge.Path = path
-			// Draw path on grid
-			for _, p := range path {
-				if cg.grid.Get(p) == cg.palette.Grass || cg.grid.Get(p) == cg.palette.Sand {
-					cg.grid.Set(p, cg.palette.Path, true)
+			cg.drawPath(path)
+		}
+	}
+
+	return nil
+}
+
+// drawPath lays the biome's path tile over open ground along a route
+func (cg *ChunkGenerator) drawPath(path []Point) {
+	for _, p := range path {
+		if cg.grid.Get(p) == cg.palette.Grass || cg.grid.Get(p) == cg.palette.Sand {
+			cg.grid.Set(p, cg.biome.PathTile, true)
+		}
+	}
+}
+
+// bridgeWater places a bridge across the water crossing that best connects
+// the land reachable from `from` toward `to`. Bridges always run straight
+// along one axis so they stay walkable. Returns false if no crossing exists.
+func (cg *ChunkGenerator) bridgeWater(from, to Point) bool {
+	region := cg.floodFillReachable(from)
+	if len(region) == 0 || region[to] {
+		return false
+	}
+
+	found := false
+	var bestStart, bestEnd Point
+	bestScore := 0
+
+	// Scan in grid order so the chosen crossing is deterministic
+	for y := 0; y < cg.size; y++ {
+		for x := 0; x < cg.size; x++ {
+			bank := Point{x, y}
+			if !region[bank] {
+				continue
+			}
+
+			for dir := North; dir <= West; dir++ {
+				dx, dy := dir.Delta()
+				first := bank.Add(dx, dy)
+				p := first
+				span := 0
+				for span < maxBridgeSpan && cg.isWater(p) {
+					p = p.Add(dx, dy)
+					span++
+				}
+				if span == 0 || !cg.grid.IsWalkable(p) || region[p] {
+					continue
+				}
+
+				score := manhattanDist(from, bank) + 2*span + manhattanDist(p, to)
+				if !found || score < bestScore {
+					found = true
+					bestScore = score
+					bestStart = first
+					bestEnd = p.Add(-dx, -dy)
 				}
 			}
 		}
 	}
 
-	return nil
+	if !found {
+		return false
+	}
+
+	bridge := NewBridge(bestStart, bestEnd)
+	cg.render(bridge)
+	cg.components = append(cg.components, bridge)
+	return true
+}
+
+func (cg *ChunkGenerator) isWater(p Point) bool {
+	tile := cg.grid.Get(p)
+	return tile == cg.palette.Water || tile == cg.palette.DeepWater
 }
 
 func (cg *ChunkGenerator) findClosestAnchor(node *Node, target Point) Point {
@@ -523,8 +925,8 @@ func (cg *ChunkGenerator) findClosestAnchor(node *Node, target Point) Point {
 func (cg *ChunkGenerator) addDecoration() {
 	// Get bounds to avoid (paths, structures)
 	avoid := make(map[Point]bool)
-	for y := 0; y < ChunkSize; y++ {
-		for x := 0; x < ChunkSize; x++ {
+	for y := 0; y < cg.size; y++ {
+		for x := 0; x < cg.size; x++ {
 			tile := cg.grid.Get(Point{x, y})
 			if tile != cg.palette.Grass {
 				avoid[Point{x, y}] = true
@@ -532,16 +934,17 @@ func (cg *ChunkGenerator) addDecoration() {
 		}
 	}
 
-	fullBounds := Bounds{0, 0, ChunkSize - 1, ChunkSize - 1}
+	fullBounds := Bounds{0, 0, cg.size - 1, cg.size - 1}
+	rng := cg.stream("decoration")
 
 	// Add trees
 	if cg.biome.TreeDensity > 0 {
-		cg.grid.Scatter(fullBounds, cg.biome.TreeType, false, cg.biome.TreeDensity, cg.rng, avoid)
+		cg.grid.Scatter(fullBounds, cg.biome.TreeType, false, cg.biome.TreeDensity, rng, avoid)
 	}
 
 	// Add bushes
 	if cg.biome.BushDensity > 0 {
-		cg.grid.Scatter(fullBounds, cg.palette.Bush, false, cg.biome.BushDensity, cg.rng, avoid)
+		cg.grid.Scatter(fullBounds, cg.palette.Bush, false, cg.biome.BushDensity, rng, avoid)
 	}
 }
 
@@ -598,66 +1001,62 @@ func (cg *ChunkGenerator) validate() error {
 }
 
 func (cg *ChunkGenerator) floodFillReachable(start Point) map[Point]bool {
-	reachable := make(map[Point]bool)
-	queue := []Point{start}
-
-	for len(queue) > 0 {
-		p := queue[0]
-		queue = queue[1:]
-
-		if reachable[p] {
-			continue
-		}
-		if !cg.grid.InBounds(p) {
-			continue
-		}
-		if !cg.grid.IsWalkable(p) {
-			continue
-		}
-
-		reachable[p] = true
-
-		for _, adj := range p.Adjacent() {
-			if !reachable[adj] {
-				queue = append(queue, adj)
-			}
-		}
-	}
-
-	return reachable
+	return cg.grid.ReachableFrom(start)
 }
 
 func (cg *ChunkGenerator) buildOutput() *ChunkDefinition {
 	// Convert zones to output format
 	zoneDefs := make([]ZoneDef, len(cg.zones))
 	for i, z := range cg.zones {
-		zoneDefs[i] = ZoneDef{
-			Name:        z.Name,
-			Description: z.Description,
-			Bounds: BoundsDef{
-				MinX: z.Bounds.MinX,
-				MaxX: z.Bounds.MaxX,
-				MinY: z.Bounds.MinY,
-				MaxY: z.Bounds.MaxY,
-			},
-			ProjectID: z.ProjectID,
-		}
+		zoneDefs[i] = zoneDef(z)
+	}
+
+	interiorDefs := make([]InteriorDef, len(cg.interiors))
+	for i, in := range cg.interiors {
+		interiorDefs[i] = in.Definition()
+	}
+
+	def := &ChunkDefinition{
+		Tiles:     cg.grid.Tiles,
+		Zones:     zoneDefs,
+		Interiors: interiorDefs,
+		Seed:      cg.config.Seed,
+		Legacy:    cg.config.Legacy,
 	}
+	if cg.grid.layers != nil {
+		def.Layers = cg.buildLayers()
+	}
+	return def
+}
 
-	return &ChunkDefinition{
-		Tiles: cg.grid.Tiles,
-		Zones: zoneDefs,
+// zoneDef converts a zone to its output format
+func zoneDef(z *Zone) ZoneDef {
+	return ZoneDef{
+		Name:        z.Name,
+		Description: z.Description,
+		Bounds: BoundsDef{
+			MinX: z.Bounds.MinX,
+			MaxX: z.Bounds.MaxX,
+			MinY: z.Bounds.MinY,
+			MaxY: z.Bounds.MaxY,
+		},
+		ProjectID: z.ProjectID,
+		Type:      z.Type,
+		Interior:  z.Interior,
+		Mask:      z.Mask,
+		Priority:  z.Priority,
+		Metadata:  z.Metadata,
 	}
 }
 
-// Simple trig for position calculation (avoiding math import for these)
-func cos(x float64) float64 {
+// Taylor-series trig, kept so legacy mode angles projects as the original did
+func legacyCos(x float64) float64 {
 	// Taylor series approximation, good enough for our purposes
 	x = mod2pi(x)
 	return 1 - x*x/2 + x*x*x*x/24 - x*x*x*x*x*x/720
 }
 
-func sin(x float64) float64 {
+func legacySin(x float64) float64 {
 	x = mod2pi(x)
 	return x - x*x*x/6 + x*x*x*x*x/120 - x*x*x*x*x*x*x/5040
 }
@@ -0,0 +1,38 @@
+package generation
+
+import (
+	"strings"
+	"testing"
+)
+
+// A chunk with nothing to connect fails validation rather than panicking,
+// whichever routing mode its biome uses
+func TestGenerateWithoutConnections(t *testing.T) {
+	for _, biome := range []BiomeType{BiomeGrassland, BiomeMountain, BiomeCoastal, BiomeForest, BiomeUrban, BiomeCastle} {
+		_, err := NewChunkGenerator(&ChunkConfig{Seed: 1, Biome: biome}).Generate()
+		if err == nil || !strings.Contains(err.Error(), "no edge connections") {
+			t.Errorf("%s: got error %v, want no edge connections", biome, err)
+		}
+	}
+}
+
+// Every biome lays out at the smallest, the default and a large chunk size,
+// with each signpost's zone inside the chunk
+func TestGenerateSizes(t *testing.T) {
+	for _, size := range []int{minChunkSize, 32, DefaultChunkSize, 64} {
+		for _, biome := range []BiomeType{BiomeGrassland, BiomeMountain, BiomeCoastal, BiomeForest, BiomeUrban, BiomeCastle} {
+			c := ChunkConfig{Seed: 3, Size: size, Biome: biome, Connections: []Direction{North, West, South},
+				Projects: []ProjectPlacement{{ProjectID: "p", Name: "P", Structure: "cabin", Size: 1}}}
+			if biome == BiomeCoastal {
+				c.Shorelines = []Direction{East}
+			}
+			def := generate(t, c)
+
+			for _, z := range def.Zones {
+				if z.Type == ZoneTypeSignpost && (z.Bounds.MinX < 0 || z.Bounds.MinY < 0 || z.Bounds.MaxX >= size || z.Bounds.MaxY >= size) {
+					t.Errorf("%s size %d: signpost zone %+v runs off the chunk", biome, size, z.Bounds)
+				}
+			}
+		}
+	}
+}
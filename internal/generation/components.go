@@ -66,19 +66,94 @@ func (s *Shoreline) Render(g *Grid, p *Palette) {
 	}
 }
 
-func (s *Shoreline) GetBounds() Bounds   { return s.bounds }
+func (s *Shoreline) GetBounds() Bounds    { return s.bounds }
 func (s *Shoreline) GetAnchors() []Anchor { return nil }
 func (s *Shoreline) GetZone() *Zone       { return nil }
 
 // MountainRange creates impassable mountains with defined passes
 type MountainRange struct {
-	bounds      Bounds
-	passes      []Point // Locations where paths can go through
-	snowLine    int     // Y offset where snow starts
+	bounds   Bounds
+	passes   []Point   // Locations where paths can go through
+	snowLine int       // Rows from the outer edge where snow lies
+	side     Direction // Edge the range backs onto (snow is on this side)
+	profile  []int     // Optional per-tile depth along the side, for jagged ridges
+	anchors  []Anchor
 }
 
 func NewMountainRange(bounds Bounds, passes []Point, snowLine int) *MountainRange {
-	return &MountainRange{bounds: bounds, passes: passes, snowLine: snowLine}
+	anchors := make([]Anchor, len(passes))
+	for i, pass := range passes {
+		anchors[i] = Anchor{Position: pass, Direction: South}
+	}
+	return &MountainRange{bounds: bounds, passes: passes, snowLine: snowLine, side: North, anchors: anchors}
+}
+
+// NewMountainRidge creates a range running along one side of the chunk,
+// spanning [from, to] along that side and starting inset tiles in from the
+// border. The inner edge wanders around depth, and each pass offset gets a
+// corridor straight through the ridge so a route to that border can get out.
+// With no passes the ridge is continuous.
+func NewMountainRidge(side Direction, from, to, inset, depth int, passOffsets []int, chunkSize int, rng *RNG) *MountainRange {
+	// Jagged inner edge: a bounded random walk around the base depth
+	profile := make([]int, to-from+1)
+	d := depth
+	for i := range profile {
+		d = max(depth-2, min(depth+2, d+rng.IntRange(-1, 1)))
+		profile[i] = d
+	}
+	reach := inset + depth + 2
+
+	var bounds Bounds
+	switch side {
+	case North:
+		bounds = Bounds{from, inset, to, reach - 1}
+	case South:
+		bounds = Bounds{from, chunkSize - reach, to, chunkSize - 1 - inset}
+	case East:
+		bounds = Bounds{chunkSize - reach, from, chunkSize - 1 - inset, to}
+	case West:
+		bounds = Bounds{inset, from, reach - 1, to}
+	}
+
+	m := &MountainRange{bounds: bounds, snowLine: 2, side: side, profile: profile}
+
+	// Each pass is a corridor from the border through to the inner edge
+	inward := side.Opposite()
+	dx, dy := inward.Delta()
+	for _, offset := range passOffsets {
+		var start Point
+		switch side {
+		case North:
+			start = Point{offset, inset}
+		case South:
+			start = Point{offset, chunkSize - 1 - inset}
+		case East:
+			start = Point{chunkSize - 1 - inset, offset}
+		case West:
+			start = Point{inset, offset}
+		}
+		for i := 0; i < reach-inset; i++ {
+			m.passes = append(m.passes, start.Add(dx*i, dy*i))
+		}
+		inner := start.Add(dx*(reach-inset), dy*(reach-inset))
+		m.anchors = append(m.anchors, Anchor{Position: inner, Direction: side})
+	}
+
+	return m
+}
+
+// depthAt returns how far pt is from the range's outer edge and its index
+// along that edge
+func (m *MountainRange) depthAt(pt Point) (depth, along int) {
+	switch m.side {
+	case South:
+		return m.bounds.MaxY - pt.Y, pt.X - m.bounds.MinX
+	case East:
+		return m.bounds.MaxX - pt.X, pt.Y - m.bounds.MinY
+	case West:
+		return pt.X - m.bounds.MinX, pt.Y - m.bounds.MinY
+	}
+	return pt.Y - m.bounds.MinY, pt.X - m.bounds.MinX
 }
 
 func (m *MountainRange) Render(g *Grid, p *Palette) {
@@ -94,15 +169,19 @@ func (m *MountainRange) Render(g *Grid, p *Palette) {
 	for y := m.bounds.MinY; y <= m.bounds.MaxY; y++ {
 		for x := m.bounds.MinX; x <= m.bounds.MaxX; x++ {
 			pt := Point{x, y}
+			depth, along := m.depthAt(pt)
+			if m.profile != nil && depth >= m.profile[along] {
+				continue
+			}
+
 			if passSet[pt] {
 				g.Set(pt, p.Path, true)
 				continue
 			}
 
-			distFromTop := y - m.bounds.MinY
-			if distFromTop < m.snowLine {
+			if depth < m.snowLine {
 				g.Set(pt, p.Snow, false)
-			} else if distFromTop < m.snowLine+2 {
+			} else if depth < m.snowLine+2 {
 				g.Set(pt, p.Peak, false)
 			} else {
 				g.Set(pt, p.Mountain, false)
@@ -111,15 +190,9 @@ func (m *MountainRange) Render(g *Grid, p *Palette) {
 	}
 }
 
-func (m *MountainRange) GetBounds() Bounds { return m.bounds }
-func (m *MountainRange) GetAnchors() []Anchor {
-	anchors := make([]Anchor, len(m.passes))
-	for i, pass := range m.passes {
-		anchors[i] = Anchor{Position: pass, Direction: South}
-	}
-	return anchors
-}
-func (m *MountainRange) GetZone() *Zone { return nil }
+func (m *MountainRange) GetBounds() Bounds    { return m.bounds }
+func (m *MountainRange) GetAnchors() []Anchor { return m.anchors }
+func (m *MountainRange) GetZone() *Zone       { return nil }
 
 // Grove creates a cluster of trees
 type Grove struct {
@@ -174,18 +247,205 @@ func (c *Clearing) GetAnchors() []Anchor {
 }
 func (c *Clearing) GetZone() *Zone { return nil }
 
+// River carves a meandering water course between two chunk edges
+type River struct {
+	course []Point // Centerline from entry to exit
+	width  int
+	bounds Bounds
+}
+
+// NewRiver builds a river from entry to exit. Both points sit on the chunk
+// border; the course runs straight in from each edge for a few tiles so the
+// water meets the neighbouring chunk's river square-on at the seam.
+func NewRiver(entry, exit Point, entrySide, exitSide Direction, width, meander, chunkSize int, rng *RNG) *River {
+	if width < 1 {
+		width = 1
+	}
+	const leadIn = 3
+
+	// Straight runs in from each edge
+	edx, edy := entrySide.Opposite().Delta()
+	xdx, xdy := exitSide.Opposite().Delta()
+	start := entry.Add(edx*leadIn, edy*leadIn)
+	end := exit.Add(xdx*leadIn, xdy*leadIn)
+
+	// Meandering is confined to the box spanned by the two ends
+	half := width / 2
+	box := Bounds{
+		max(min(start.X, end.X)-meander, half+1),
+		max(min(start.Y, end.Y)-meander, half+1),
+		min(max(start.X, end.X)+meander, chunkSize-half-2),
+		min(max(start.Y, end.Y)+meander, chunkSize-half-2),
+	}
+
+	course := make([]Point, 0)
+	for i := 0; i < leadIn; i++ {
+		course = append(course, entry.Add(edx*i, edy*i))
+	}
+	course = append(course, meanderCourse(start, end, box, 4*chunkSize, rng)...)
+	for i := leadIn - 1; i >= 0; i-- {
+		course = append(course, exit.Add(xdx*i, xdy*i))
+	}
+
+	r := &River{course: course, width: width}
+	r.bounds = Bounds{entry.X, entry.Y, entry.X, entry.Y}
+	for _, pt := range course {
+		r.bounds.MinX = min(r.bounds.MinX, pt.X-half)
+		r.bounds.MinY = min(r.bounds.MinY, pt.Y-half)
+		r.bounds.MaxX = max(r.bounds.MaxX, pt.X+half)
+		r.bounds.MaxY = max(r.bounds.MaxY, pt.Y+half)
+	}
+	return r
+}
+
+// meanderCourse walks from start to end, mostly heading for the target but
+// occasionally wandering sideways within box. After maxSteps the walk stops
+// wandering so it always terminates.
+func meanderCourse(start, end Point, box Bounds, maxSteps int, rng *RNG) []Point {
+	course := []Point{start}
+	cur, prev := start, start
+
+	for steps := 0; cur != end; steps++ {
+		var next Point
+		remX, remY := end.X-cur.X, end.Y-cur.Y
+
+		if steps < maxSteps && rng.Float64() < 0.3 {
+			// Wander in a random direction, never straight back
+			dx, dy := Direction(rng.Intn(4)).Delta()
+			next = cur.Add(dx, dy)
+			if !box.Contains(next) || next == prev {
+				continue
+			}
+		} else if remX != 0 && (remY == 0 || rng.Intn(abs(remX)+abs(remY)) < abs(remX)) {
+			next = cur.Add(sign(remX), 0)
+		} else {
+			next = cur.Add(0, sign(remY))
+		}
+
+		prev, cur = cur, next
+		course = append(course, cur)
+	}
+
+	return course
+}
+
+// waterTiles returns every tile covered by the river's channel
+func (r *River) waterTiles() map[Point]bool {
+	half := r.width / 2
+	water := make(map[Point]bool)
+	for _, pt := range r.course {
+		for dy := -half; dy <= half; dy++ {
+			for dx := -half; dx <= half; dx++ {
+				water[pt.Add(dx, dy)] = true
+			}
+		}
+	}
+	return water
+}
+
+func (r *River) Render(g *Grid, p *Palette) {
+	water := r.waterTiles()
+	for pt := range water {
+		g.Set(pt, p.Water, false)
+	}
+
+	// Deep channel down the middle of wider rivers
+	if r.width >= 3 {
+		for _, pt := range r.course {
+			g.Set(pt, p.DeepWater, false)
+		}
+	}
+
+	// Sandy banks
+	for pt := range water {
+		for _, adj := range pt.Adjacent() {
+			if !water[adj] && g.Get(adj) == p.Grass {
+				g.Set(adj, p.Sand, true)
+			}
+		}
+	}
+}
+
+func (r *River) GetBounds() Bounds    { return r.bounds }
+func (r *River) GetAnchors() []Anchor { return nil }
+func (r *River) GetZone() *Zone       { return nil }
+
+// Lake creates an irregular body of water from overlapping lobes
+type Lake struct {
+	center Point
+	radius int
+	tiles  map[Point]bool
+}
+
+func NewLake(center Point, radius int, rng *RNG) *Lake {
+	l := &Lake{center: center, radius: radius, tiles: make(map[Point]bool)}
+
+	l.addLobe(center, radius)
+	lobes := 2 + rng.Intn(3)
+	for i := 0; i < lobes; i++ {
+		offset := Point{rng.IntRange(-radius/2, radius/2), rng.IntRange(-radius/2, radius/2)}
+		l.addLobe(center.Add(offset.X, offset.Y), rng.IntRange(max(radius/2, 1), radius))
+	}
+	return l
+}
+
+func (l *Lake) addLobe(c Point, r int) {
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			if dx*dx+dy*dy <= r*r {
+				l.tiles[c.Add(dx, dy)] = true
+			}
+		}
+	}
+}
+
+func (l *Lake) Render(g *Grid, p *Palette) {
+	for pt := range l.tiles {
+		// Deep water where the lake extends at least two tiles in every direction
+		deep := l.tiles[pt.Add(0, -2)] && l.tiles[pt.Add(2, 0)] &&
+			l.tiles[pt.Add(0, 2)] && l.tiles[pt.Add(-2, 0)]
+		if deep {
+			g.Set(pt, p.DeepWater, false)
+		} else {
+			g.Set(pt, p.Water, false)
+		}
+	}
+
+	// Sandy shore
+	for pt := range l.tiles {
+		for _, adj := range pt.Adjacent() {
+			if !l.tiles[adj] && g.Get(adj) == p.Grass {
+				g.Set(adj, p.Sand, true)
+			}
+		}
+	}
+}
+
+func (l *Lake) GetBounds() Bounds {
+	b := Bounds{l.center.X, l.center.Y, l.center.X, l.center.Y}
+	for pt := range l.tiles {
+		b.MinX = min(b.MinX, pt.X)
+		b.MinY = min(b.MinY, pt.Y)
+		b.MaxX = max(b.MaxX, pt.X)
+		b.MaxY = max(b.MaxY, pt.Y)
+	}
+	return b.Expand(1)
+}
+func (l *Lake) GetAnchors() []Anchor { return nil }
+func (l *Lake) GetZone() *Zone       { return nil }
+
 // ---- Structure Components ----
 
-// Building creates a rectangular structure with walls and a door
+// Building creates a rectangular structure with walls and one or more doors
 type Building struct {
-	bounds      Bounds
-	style       string // "stone", "white", "wood"
-	entranceDir Direction
-	zone        *Zone
+	bounds    Bounds
+	style     string // "stone", "white", "wood"
+	entrances []Direction
+	zone      *Zone
 }
 
-func NewBuilding(bounds Bounds, style string, entranceDir Direction, zone *Zone) *Building {
-	return &Building{bounds: bounds, style: style, entranceDir: entranceDir, zone: zone}
+func NewBuilding(bounds Bounds, style string, entrances []Direction, zone *Zone) *Building {
+	return &Building{bounds: bounds, style: style, entrances: entrances, zone: zone}
 }
 
 func (b *Building) Render(g *Grid, p *Palette) {
@@ -205,18 +465,17 @@ func (b *Building) Render(g *Grid, p *Palette) {
 	// Draw walls
 	g.RectOutline(b.bounds, wallTile, false)
 
-	// Add windows on walls (not on corners or door side)
-	doorPos := b.getDoorPosition()
+	// Add windows on walls (not on corners or door sides)
 	width := b.bounds.MaxX - b.bounds.MinX
 	height := b.bounds.MaxY - b.bounds.MinY
 
 	// Windows on horizontal walls
 	if height >= 4 {
 		for x := b.bounds.MinX + 2; x <= b.bounds.MaxX-2; x += 2 {
-			if b.entranceDir != North {
+			if !hasDirection(b.entrances, North) {
 				g.Set(Point{x, b.bounds.MinY}, p.Window, false)
 			}
-			if b.entranceDir != South {
+			if !hasDirection(b.entrances, South) {
 				g.Set(Point{x, b.bounds.MaxY}, p.Window, false)
 			}
 		}
@@ -225,52 +484,36 @@ func (b *Building) Render(g *Grid, p *Palette) {
 	// Windows on vertical walls
 	if width >= 4 {
 		for y := b.bounds.MinY + 2; y <= b.bounds.MaxY-2; y += 2 {
-			if b.entranceDir != West {
+			if !hasDirection(b.entrances, West) {
 				g.Set(Point{b.bounds.MinX, y}, p.Window, false)
 			}
-			if b.entranceDir != East {
+			if !hasDirection(b.entrances, East) {
 				g.Set(Point{b.bounds.MaxX, y}, p.Window, false)
 			}
 		}
 	}
 
-	// Place door on entrance side
-	g.Set(doorPos, p.Door, true)
-}
-
-func (b *Building) getDoorPosition() Point {
-	center := b.bounds.Center()
-	switch b.entranceDir {
-	case North:
-		return Point{center.X, b.bounds.MinY}
-	case South:
-		return Point{center.X, b.bounds.MaxY}
-	case East:
-		return Point{b.bounds.MaxX, center.Y}
-	case West:
-		return Point{b.bounds.MinX, center.Y}
+	// Place a door on each entrance side
+	for _, dir := range b.entrances {
+		g.Set(b.bounds.SideCenter(dir), p.Door, true)
 	}
-	return Point{center.X, b.bounds.MaxY}
 }
 
 func (b *Building) GetBounds() Bounds { return b.bounds }
-func (b *Building) GetAnchors() []Anchor {
-	door := b.getDoorPosition()
-	dx, dy := b.entranceDir.Delta()
-	// Anchor is one tile outside the door
-	return []Anchor{{Position: door.Add(dx, dy), Direction: b.entranceDir.Opposite()}}
-}
-func (b *Building) GetZone() *Zone { return b.zone }
+
+// GetAnchors returns one anchor per door, one tile outside it
+func (b *Building) GetAnchors() []Anchor { return doorAnchors(b.bounds, b.entrances) }
+func (b *Building) GetZone() *Zone       { return b.zone }
 
 // Cabin creates a small rustic structure with chimney
 type Cabin struct {
-	bounds      Bounds
-	entranceDir Direction
-	zone        *Zone
+	bounds    Bounds
+	entrances []Direction
+	zone      *Zone
 }
 
-func NewCabin(bounds Bounds, entranceDir Direction, zone *Zone) *Cabin {
-	return &Cabin{bounds: bounds, entranceDir: entranceDir, zone: zone}
+func NewCabin(bounds Bounds, entrances []Direction, zone *Zone) *Cabin {
+	return &Cabin{bounds: bounds, entrances: entrances, zone: zone}
 }
 
 func (c *Cabin) Render(g *Grid, p *Palette) {
@@ -287,44 +530,26 @@ func (c *Cabin) Render(g *Grid, p *Palette) {
 		g.Set(chimneyPos, p.Chimney, false)
 	}
 
-	// Place door
-	doorPos := c.getDoorPosition()
-	g.Set(doorPos, p.Door, true)
-}
-
-func (c *Cabin) getDoorPosition() Point {
-	center := c.bounds.Center()
-	switch c.entranceDir {
-	case North:
-		return Point{center.X, c.bounds.MinY}
-	case South:
-		return Point{center.X, c.bounds.MaxY}
-	case East:
-		return Point{c.bounds.MaxX, center.Y}
-	case West:
-		return Point{c.bounds.MinX, center.Y}
+	// Place doors
+	for _, dir := range c.entrances {
+		g.Set(c.bounds.SideCenter(dir), p.Door, true)
 	}
-	return Point{center.X, c.bounds.MaxY}
 }
 
-func (c *Cabin) GetBounds() Bounds { return c.bounds }
-func (c *Cabin) GetAnchors() []Anchor {
-	door := c.getDoorPosition()
-	dx, dy := c.entranceDir.Delta()
-	return []Anchor{{Position: door.Add(dx, dy), Direction: c.entranceDir.Opposite()}}
-}
-func (c *Cabin) GetZone() *Zone { return c.zone }
+func (c *Cabin) GetBounds() Bounds    { return c.bounds }
+func (c *Cabin) GetAnchors() []Anchor { return doorAnchors(c.bounds, c.entrances) }
+func (c *Cabin) GetZone() *Zone       { return c.zone }
 
 // Tower creates a larger central structure
 type Tower struct {
-	center      Point
-	radius      int
-	entranceDir Direction
-	zone        *Zone
+	center    Point
+	radius    int
+	entrances []Direction
+	zone      *Zone
 }
 
-func NewTower(center Point, radius int, entranceDir Direction, zone *Zone) *Tower {
-	return &Tower{center: center, radius: radius, entranceDir: entranceDir, zone: zone}
+func NewTower(center Point, radius int, entrances []Direction, zone *Zone) *Tower {
+	return &Tower{center: center, radius: radius, entrances: entrances, zone: zone}
 }
 
 func (t *Tower) Render(g *Grid, p *Palette) {
@@ -349,23 +574,10 @@ func (t *Tower) Render(g *Grid, p *Palette) {
 		g.Set(t.center, p.Star, true)
 	}
 
-	// Place door
-	doorPos := t.getDoorPosition()
-	g.Set(doorPos, p.Door, true)
-}
-
-func (t *Tower) getDoorPosition() Point {
-	switch t.entranceDir {
-	case North:
-		return Point{t.center.X, t.center.Y - t.radius}
-	case South:
-		return Point{t.center.X, t.center.Y + t.radius}
-	case East:
-		return Point{t.center.X + t.radius, t.center.Y}
-	case West:
-		return Point{t.center.X - t.radius, t.center.Y}
+	// Place doors
+	for _, dir := range t.entrances {
+		g.Set(bounds.SideCenter(dir), p.Door, true)
 	}
-	return Point{t.center.X, t.center.Y + t.radius}
 }
 
 func (t *Tower) GetBounds() Bounds {
@@ -373,11 +585,7 @@ func (t *Tower) GetBounds() Bounds {
 		t.center.X + t.radius, t.center.Y + t.radius}
 }
 
-func (t *Tower) GetAnchors() []Anchor {
-	door := t.getDoorPosition()
-	dx, dy := t.entranceDir.Delta()
-	return []Anchor{{Position: door.Add(dx, dy), Direction: t.entranceDir.Opposite()}}
-}
+func (t *Tower) GetAnchors() []Anchor { return doorAnchors(t.GetBounds(), t.entrances) }
 
 func (t *Tower) GetZone() *Zone { return t.zone }
 
@@ -425,42 +633,13 @@ func (c *Courtyard) Render(g *Grid, p *Palette) {
 
 	// Place entrances (gates)
 	for _, dir := range c.entrances {
-		var gatePos Point
-		switch dir {
-		case North:
-			gatePos = Point{center.X, c.bounds.MinY}
-		case South:
-			gatePos = Point{center.X, c.bounds.MaxY}
-		case East:
-			gatePos = Point{c.bounds.MaxX, center.Y}
-		case West:
-			gatePos = Point{c.bounds.MinX, center.Y}
-		}
-		g.Set(gatePos, p.Door, true)
+		g.Set(c.bounds.SideCenter(dir), p.Door, true)
 	}
 }
 
 func (c *Courtyard) GetBounds() Bounds { return c.bounds }
 
-func (c *Courtyard) GetAnchors() []Anchor {
-	anchors := make([]Anchor, len(c.entrances))
-	center := c.bounds.Center()
-	for i, dir := range c.entrances {
-		var pos Point
-		switch dir {
-		case North:
-			pos = Point{center.X, c.bounds.MinY - 1}
-		case South:
-			pos = Point{center.X, c.bounds.MaxY + 1}
-		case East:
-			pos = Point{c.bounds.MaxX + 1, center.Y}
-		case West:
-			pos = Point{c.bounds.MinX - 1, center.Y}
-		}
-		anchors[i] = Anchor{Position: pos, Direction: dir.Opposite()}
-	}
-	return anchors
-}
+func (c *Courtyard) GetAnchors() []Anchor { return doorAnchors(c.bounds, c.entrances) }
 
 func (c *Courtyard) GetZone() *Zone { return c.zone }
 
@@ -655,7 +834,7 @@ func (s *ScatterDecor) Render(g *Grid, p *Palette) {
 	g.Scatter(s.bounds, s.tile, false, s.density, s.rng, nil)
 }
 
-func (s *ScatterDecor) GetBounds() Bounds  { return s.bounds }
+func (s *ScatterDecor) GetBounds() Bounds    { return s.bounds }
 func (s *ScatterDecor) GetAnchors() []Anchor { return nil }
 func (s *ScatterDecor) GetZone() *Zone       { return nil }
 
@@ -686,14 +865,22 @@ type Signpost struct {
 	zone      *Zone
 }
 
+// NewSignpost creates a signpost pointing toward destName, which may be
+// empty if unknown. destHint is the sign's text, one line per line.
 func NewSignpost(position Point, direction Direction, destName, destHint string) *Signpost {
+	name := "Signpost"
+	if destName != "" {
+		name = "Signpost to " + destName
+	}
 	return &Signpost{
 		position:  position,
 		direction: direction,
 		zone: &Zone{
-			Name:        "Signpost",
+			Name:        name,
 			Description: destHint,
 			Bounds:      Bounds{position.X - 1, position.Y - 1, position.X + 1, position.Y + 1},
+			Type:        ZoneTypeSignpost,
+			Priority:    zonePrioritySignpost,
 		},
 	}
 }
@@ -797,7 +984,7 @@ func (r *Ruins) Render(g *Grid, p *Palette) {
 	for x := r.bounds.MinX; x <= r.bounds.MaxX; x++ {
 		for y := r.bounds.MinY; y <= r.bounds.MaxY; y++ {
 			isEdge := x == r.bounds.MinX || x == r.bounds.MaxX ||
-			          y == r.bounds.MinY || y == r.bounds.MaxY
+				y == r.bounds.MinY || y == r.bounds.MaxY
 			if isEdge {
 				if r.rng.Float64() > r.decay {
 					g.Set(Point{x, y}, p.Building, false)
@@ -811,7 +998,7 @@ func (r *Ruins) Render(g *Grid, p *Palette) {
 	}
 }
 
-func (r *Ruins) GetBounds() Bounds    { return r.bounds }
+func (r *Ruins) GetBounds() Bounds { return r.bounds }
 func (r *Ruins) GetAnchors() []Anchor {
 	center := r.bounds.Center()
 	return []Anchor{{Position: Point{center.X, r.bounds.MaxY + 1}, Direction: North}}
@@ -819,6 +1006,27 @@ func (r *Ruins) GetAnchors() []Anchor {
 func (r *Ruins) GetZone() *Zone { return nil }
 
 // Helper functions
+
+// doorAnchors returns an anchor one tile outside the middle of each entrance
+// side of b, facing back toward the door
+func doorAnchors(b Bounds, entrances []Direction) []Anchor {
+	anchors := make([]Anchor, len(entrances))
+	for i, dir := range entrances {
+		dx, dy := dir.Delta()
+		anchors[i] = Anchor{Position: b.SideCenter(dir).Add(dx, dy), Direction: dir.Opposite()}
+	}
+	return anchors
+}
+
+func hasDirection(dirs []Direction, d Direction) bool {
+	for _, dir := range dirs {
+		if dir == d {
+			return true
+		}
+	}
+	return false
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
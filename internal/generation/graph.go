@@ -1,16 +1,33 @@
 package generation
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"sort"
+)
 
 // NodeType identifies what kind of node this is in the graph
 type NodeType int
 
 const (
-	NodeEdgePort   NodeType = iota // Entry/exit point at chunk border
-	NodeComponent                  // A placed component (building, shrine, etc.)
-	NodeHub                        // Central connection point
+	NodeEdgePort  NodeType = iota // Entry/exit point at chunk border
+	NodeComponent                 // A placed component (building, shrine, etc.)
+	NodeHub                       // Central connection point
 )
 
+// String returns the name of the node type
+func (t NodeType) String() string {
+	switch t {
+	case NodeEdgePort:
+		return "edge_port"
+	case NodeComponent:
+		return "component"
+	case NodeHub:
+		return "hub"
+	}
+	return "unknown"
+}
+
 // Node represents a connectable element in the chunk graph
 type Node struct {
 	ID       string
@@ -27,6 +44,10 @@ type Edge struct {
 	Weight   float64 // Cost/distance (for MST calculation)
 	Required bool    // Must this edge exist for validity?
 	Path     []Point // Realized path on the grid (filled during rendering)
+
+	// Anchors the path was routed between (set once routing is attempted)
+	FromAnchor, ToAnchor Point
+	Routed               bool
 }
 
 // Graph manages nodes and edges for chunk generation
@@ -83,6 +104,24 @@ func (g *Graph) AddEdge(fromID, toID string, required bool) error {
 	return nil
 }
 
+// ClearEdges removes every edge, leaving the nodes in place
+func (g *Graph) ClearEdges() {
+	g.Edges = make([]*Edge, 0)
+	for id := range g.Adjacent {
+		g.Adjacent[id] = make([]string, 0)
+	}
+}
+
+// NodeIDs returns the IDs of all nodes in sorted order
+func (g *Graph) NodeIDs() []string {
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // GetEdge returns the edge between two nodes if it exists
 func (g *Graph) GetEdge(fromID, toID string) *Edge {
 	for _, e := range g.Edges {
@@ -222,6 +261,45 @@ func (g *Graph) MST() []*Edge {
 	return mst
 }
 
+// routeWeight returns the total weight of the lightest route between two
+// nodes over the given edges, or +Inf if they aren't connected
+func routeWeight(edges []*Edge, from, to string) float64 {
+	dist := map[string]float64{from: 0}
+	done := make(map[string]bool)
+
+	for {
+		// Pick the closest unfinished node (graphs here are tiny)
+		current, best := "", math.Inf(1)
+		for id, d := range dist {
+			if !done[id] && d < best {
+				current, best = id, d
+			}
+		}
+		if current == "" {
+			return math.Inf(1)
+		}
+		if current == to {
+			return best
+		}
+		done[current] = true
+
+		for _, e := range edges {
+			next := ""
+			if e.From == current {
+				next = e.To
+			} else if e.To == current {
+				next = e.From
+			}
+			if next == "" {
+				continue
+			}
+			if d, ok := dist[next]; !ok || best+e.Weight < d {
+				dist[next] = best + e.Weight
+			}
+		}
+	}
+}
+
 func manhattanDist(a, b Point) int {
 	dx := a.X - b.X
 	dy := a.Y - b.Y
@@ -0,0 +1,155 @@
+package generation
+
+import (
+	"fmt"
+)
+
+// Interior is the walkable inside of a project structure. The player enters
+// through any of the structure's doors and appears at the spawn point; the
+// exit door leads back out.
+type Interior struct {
+	ID    string
+	Name  string
+	grid  *Grid
+	zones []*Zone
+	spawn Point
+}
+
+// hasInterior reports whether a structure type can be entered. Courtyards
+// and shrines are open to the sky, so there is nothing to go inside.
+func hasInterior(structure string) bool {
+	return structure != "courtyard" && structure != "shrine"
+}
+
+// NewInterior lays out the inside of a project structure: a hall with
+// shelves along the back wall and a pedestal per artifact, plus a study with
+// a table when the structure is large enough to split into two rooms.
+func NewInterior(proj ProjectPlacement, p *Palette, rng *RNG) (*Interior, error) {
+	size := max(proj.Size, 1)
+	w, h := 13+4*size, 9+2*size
+	if proj.Structure == "tower" {
+		h = w - 4
+	}
+
+	wall, floor := p.Building, p.Cobblestone
+	if proj.Structure == "cabin" {
+		wall, floor = p.WoodWall, p.WoodFloor
+	}
+
+	g := NewGrid(w, h, floor, true)
+	g.RectOutline(Bounds{0, 0, w - 1, h - 1}, wall, false)
+
+	in := &Interior{
+		ID:    proj.ProjectID,
+		Name:  proj.Name,
+		grid:  g,
+		zones: make([]*Zone, 0),
+		spawn: Point{w / 2, h - 2},
+	}
+
+	// Exit door in the middle of the front wall
+	exit := Point{w / 2, h - 1}
+	g.Set(exit, p.Door, true)
+	in.zones = append(in.zones, &Zone{
+		Name:        "Exit",
+		Description: "The way back outside.",
+		Bounds:      Bounds{exit.X, exit.Y, exit.X, exit.Y},
+		Type:        ZoneTypeExit,
+		Priority:    zonePriorityDoor,
+	})
+
+	hall := Bounds{1, 1, w - 2, h - 2}
+
+	// Larger structures get a study partitioned off the east end
+	if w >= 21 {
+		divider := w - 8
+		g.Line(Point{divider, 1}, Point{divider, h - 2}, wall, false)
+		g.Set(Point{divider, h / 2}, floor, true)
+		hall.MaxX = divider - 1
+
+		study := Bounds{divider + 1, 1, w - 2, h - 2}
+		c := study.Center()
+		g.Rect(Bounds{c.X - 1, c.Y + 1, c.X + 1, c.Y + 1}, p.Table, false)
+		for x := study.MinX; x <= study.MaxX; x++ {
+			if rng.Float64() < 0.7 {
+				g.Set(Point{x, study.MinY}, p.Shelf, false)
+			}
+		}
+	}
+
+	// Shelves along the hall's back wall
+	for x := hall.MinX + 1; x <= hall.MaxX-1; x++ {
+		if rng.Float64() < 0.6 {
+			g.Set(Point{x, hall.MinY}, p.Shelf, false)
+		}
+	}
+
+	artifacts := proj.Artifacts
+	if len(artifacts) == 0 {
+		artifacts = []string{proj.Name}
+	}
+
+	// Pedestals stand in centred rows three tiles apart, so each one can be
+	// walked around and has its own 3x3 zone
+	perRow := (hall.Width() - 2) / 3
+	row := hall.MinY + 3
+	for i := 0; i < len(artifacts); i += perRow {
+		if row > hall.MaxY-2 {
+			return nil, fmt.Errorf("interior %s: no room for %d artifacts", in.ID, len(artifacts))
+		}
+
+		n := min(perRow, len(artifacts)-i)
+		x := hall.Center().X - (n-1)*3/2
+		for _, artifact := range artifacts[i : i+n] {
+			pos := Point{x, row}
+			g.Set(pos, p.Pedestal, false)
+			in.zones = append(in.zones, &Zone{
+				Name:        artifact,
+				Description: fmt.Sprintf("An exhibit from %s.", proj.Name),
+				Bounds:      Bounds{pos.X - 1, pos.Y - 1, pos.X + 1, pos.Y + 1},
+				ProjectID:   proj.ProjectID,
+				Type:        ZoneTypeProject,
+			})
+			x += 3
+		}
+		row += 3
+	}
+
+	return in, nil
+}
+
+// Validate checks that every zone in the interior can be reached from the
+// spawn point
+func (in *Interior) Validate() error {
+	reachable := in.grid.ReachableFrom(in.spawn)
+	for _, zone := range in.zones {
+		found := false
+		for y := zone.Bounds.MinY; y <= zone.Bounds.MaxY && !found; y++ {
+			for x := zone.Bounds.MinX; x <= zone.Bounds.MaxX; x++ {
+				if reachable[Point{x, y}] {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("interior %s: zone %q not reachable from entrance", in.ID, zone.Name)
+		}
+	}
+	return nil
+}
+
+// Definition converts the interior to its output format
+func (in *Interior) Definition() InteriorDef {
+	zones := make([]ZoneDef, len(in.zones))
+	for i, z := range in.zones {
+		zones[i] = zoneDef(z)
+	}
+	return InteriorDef{
+		ID:    in.ID,
+		Name:  in.Name,
+		Tiles: in.grid.Tiles,
+		Zones: zones,
+		Spawn: [2]int{in.spawn.X, in.spawn.Y},
+	}
+}
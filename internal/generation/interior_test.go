@@ -0,0 +1,104 @@
+package generation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Every interior can be walked from its spawn to its exit and every exhibit,
+// and the same seed lays it out the same way
+func TestInteriorLayout(t *testing.T) {
+	p := DefaultPalette()
+	for _, structure := range []string{"building", "tower", "cabin"} {
+		for size := 1; size <= 3; size++ {
+			for n := 0; n <= 4; n++ {
+				proj := ProjectPlacement{ProjectID: "p", Name: "P", Structure: structure, Size: size}
+				for i := 0; i < n; i++ {
+					proj.Artifacts = append(proj.Artifacts, fmt.Sprintf("artifact %d", i))
+				}
+				name := fmt.Sprintf("%s size %d with %d artifacts", structure, size, n)
+
+				in, err := NewInterior(proj, p, NewRNG(7))
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+					continue
+				}
+				if err := in.Validate(); err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+				if !in.grid.InBounds(in.spawn) || !in.grid.IsWalkable(in.spawn) {
+					t.Errorf("%s: spawn %v is not a walkable tile", name, in.spawn)
+				}
+
+				exits, exhibits := 0, 0
+				for _, z := range in.zones {
+					switch z.Type {
+					case ZoneTypeExit:
+						exits++
+						if door := (Point{z.Bounds.MinX, z.Bounds.MinY}); in.grid.Get(door) != p.Door {
+							t.Errorf("%s: exit at %v is %q, want a door", name, door, in.grid.Get(door))
+						}
+					case ZoneTypeProject:
+						exhibits++
+					}
+				}
+				if exits != 1 || exhibits != max(n, 1) {
+					t.Errorf("%s: got %d exits and %d exhibits, want 1 and %d", name, exits, exhibits, max(n, 1))
+				}
+
+				again, _ := NewInterior(proj, p, NewRNG(7))
+				if !reflect.DeepEqual(in.Definition(), again.Definition()) {
+					t.Errorf("%s: two layouts on the same seed differ", name)
+				}
+			}
+		}
+	}
+}
+
+// More artifacts than the hall can hold is an error, not a crowded room
+func TestInteriorTooManyArtifacts(t *testing.T) {
+	proj := ProjectPlacement{ProjectID: "p", Name: "P", Structure: "cabin", Size: 1, Artifacts: make([]string, 50)}
+	_, err := NewInterior(proj, DefaultPalette(), NewRNG(7))
+	if err == nil || !strings.Contains(err.Error(), "no room for 50 artifacts") {
+		t.Errorf("got error %v, want no room for 50 artifacts", err)
+	}
+}
+
+// In a generated chunk every door leads to an interior that exists, every
+// interior has a door, and courtyards and shrines have none
+func TestGeneratedInteriors(t *testing.T) {
+	for _, c := range smallWorld() {
+		def := generate(t, c)
+
+		interiors := make(map[string]InteriorDef)
+		for _, in := range def.Interiors {
+			interiors[in.ID] = in
+			x, y := in.Spawn[0], in.Spawn[1]
+			if y < 0 || y >= len(in.Tiles) || x < 0 || x >= len(in.Tiles[y]) {
+				t.Errorf("chunk %d,%d: interior %q spawns at %v, outside its tiles", c.ChunkX, c.ChunkY, in.ID, in.Spawn)
+			}
+		}
+
+		doors := make(map[string]bool)
+		for _, z := range def.Zones {
+			if z.Type != ZoneTypeInterior {
+				continue
+			}
+			doors[z.Interior] = true
+			if _, ok := interiors[z.Interior]; !ok {
+				t.Errorf("chunk %d,%d: door %q leads to missing interior %q", c.ChunkX, c.ChunkY, z.Name, z.Interior)
+			}
+		}
+
+		for _, proj := range c.Projects {
+			if got, want := doors[proj.ProjectID], hasInterior(proj.Structure); got != want {
+				t.Errorf("chunk %d,%d: %s %q has a door = %v, want %v", c.ChunkX, c.ChunkY, proj.Structure, proj.ProjectID, got, want)
+			}
+		}
+		if len(interiors) != len(doors) {
+			t.Errorf("chunk %d,%d: got %d interiors for %d doors", c.ChunkX, c.ChunkY, len(interiors), len(doors))
+		}
+	}
+}
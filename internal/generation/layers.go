@@ -0,0 +1,146 @@
+package generation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LayersDef matches the JSON layers format: optional per-tile data parallel
+// to a chunk's tiles, each layer the same size as the tile grid
+type LayersDef struct {
+	Ground    [][]string `json:"ground,omitempty"`    // Terrain under whatever stands on it
+	Overlay   [][]string `json:"overlay,omitempty"`   // What stands on the ground, "" for nothing
+	Collision []string   `json:"collision,omitempty"` // One string per row: '#' blocks movement, '.' doesn't
+	Owner     [][]int    `json:"owner,omitempty"`     // Index into Owners, -1 for none
+	Owners    []string   `json:"owners,omitempty"`    // IDs of the components that drew tiles
+	Elevation [][]int    `json:"elevation,omitempty"` // Height relative to open ground
+}
+
+// groundElevation gives the height of each kind of ground tile. Anything
+// else is an overlay drawn on top of the ground.
+var groundElevation = map[string]int{
+	"deep_water": -2,
+	"water":      -1,
+	"sand":       0,
+	"grass":      0,
+	"snow":       1,
+	"mountain":   2,
+	"peak":       3,
+}
+
+// gridLayers records what the tile layers need while a grid is drawn
+type gridLayers struct {
+	ground    [][]string
+	owner     [][]string
+	drawing   string         // Component currently rendering, "" for none
+	elevation map[string]int // Ground glyphs and their heights
+}
+
+// TrackLayers starts recording ground and ownership for every tile drawn
+// from now on. The tiles already on the grid are taken as ground.
+func (g *Grid) TrackLayers(p *Palette) {
+	layers := &gridLayers{
+		ground:    make([][]string, g.Height),
+		owner:     make([][]string, g.Height),
+		elevation: make(map[string]int),
+	}
+	for _, e := range p.entries() {
+		if h, ok := groundElevation[e.Type]; ok {
+			layers.elevation[*e.Glyph] = h
+		}
+	}
+	for y := 0; y < g.Height; y++ {
+		layers.ground[y] = append([]string(nil), g.Tiles[y]...)
+		layers.owner[y] = make([]string, g.Width)
+	}
+	g.layers = layers
+}
+
+// record notes a tile being drawn at p
+func (l *gridLayers) record(p Point, tile string) {
+	if _, ok := l.elevation[tile]; ok {
+		l.ground[p.Y][p.X] = tile
+	}
+	if l.drawing != "" {
+		l.owner[p.Y][p.X] = l.drawing
+	}
+}
+
+// render draws a component onto the grid, crediting it with the tiles it
+// touches when layers are tracked
+func (cg *ChunkGenerator) render(comp Component) {
+	if cg.grid.layers != nil {
+		cg.grid.layers.drawing = cg.componentID(comp)
+		defer func() { cg.grid.layers.drawing = "" }()
+	}
+	comp.Render(cg.grid, cg.palette)
+}
+
+// componentID names a component for the owner layer: its kind and project
+// for structures, otherwise its kind and a count in order of first drawing
+func (cg *ChunkGenerator) componentID(comp Component) string {
+	if id, ok := cg.componentIDs[comp]; ok {
+		return id
+	}
+
+	kind := strings.ToLower(strings.TrimPrefix(fmt.Sprintf("%T", comp), "*generation."))
+	var id string
+	if zone := comp.GetZone(); zone != nil && zone.ProjectID != "" {
+		id = kind + ":" + zone.ProjectID
+	} else {
+		cg.componentKinds[kind]++
+		id = fmt.Sprintf("%s_%d", kind, cg.componentKinds[kind])
+	}
+	cg.componentIDs[comp] = id
+	return id
+}
+
+// buildLayers converts the tracked grid layers to their output format
+func (cg *ChunkGenerator) buildLayers() *LayersDef {
+	g, l := cg.grid, cg.grid.layers
+	out := &LayersDef{
+		Ground:    make([][]string, g.Height),
+		Overlay:   make([][]string, g.Height),
+		Collision: make([]string, g.Height),
+		Owner:     make([][]int, g.Height),
+		Owners:    make([]string, 0),
+		Elevation: make([][]int, g.Height),
+	}
+
+	owners := make(map[string]int)
+	for y := 0; y < g.Height; y++ {
+		out.Ground[y] = l.ground[y]
+		out.Overlay[y] = make([]string, g.Width)
+		out.Owner[y] = make([]int, g.Width)
+		out.Elevation[y] = make([]int, g.Width)
+
+		var collision strings.Builder
+		for x := 0; x < g.Width; x++ {
+			if tile := g.Tiles[y][x]; tile != l.ground[y][x] {
+				out.Overlay[y][x] = tile
+			}
+
+			if g.Walkable[y][x] {
+				collision.WriteByte('.')
+			} else {
+				collision.WriteByte('#')
+			}
+
+			out.Owner[y][x] = -1
+			if id := l.owner[y][x]; id != "" {
+				i, ok := owners[id]
+				if !ok {
+					i = len(out.Owners)
+					owners[id] = i
+					out.Owners = append(out.Owners, id)
+				}
+				out.Owner[y][x] = i
+			}
+
+			out.Elevation[y][x] = l.elevation[l.ground[y][x]]
+		}
+		out.Collision[y] = collision.String()
+	}
+
+	return out
+}
@@ -0,0 +1,470 @@
+package generation
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Palette defines the tiles available for a biome
+type Palette struct {
+	// Terrain
+	Grass     string
+	Sand      string
+	Water     string
+	DeepWater string
+	Snow      string
+	Mountain  string
+	Peak      string
+
+	// Vegetation
+	Tree     string
+	PineTree string
+	Bush     string
+
+	// Structures
+	Building      string
+	WhiteBuilding string
+	WoodWall      string
+	Door          string
+	Pillar        string
+
+	// Infrastructure
+	Path        string
+	Dirt        string
+	Cobblestone string
+	Dock        string
+	Bridge      string
+
+	// Special
+	Star   string
+	Marker string
+	Empty  string
+
+	// Additional details
+	Window    string
+	WoodFloor string
+	Chimney   string
+
+	// Furniture (interiors)
+	Shelf    string
+	Table    string
+	Pedestal string
+
+	// Presentation - how the world manifest describes each glyph
+	Name   string
+	Chars  map[string]string               // Character drawn, by tile type, where it isn't the glyph
+	Colors map[string]string               // Color by tile type
+	Biomes map[BiomeType]map[string]string // Glyph overrides by biome, then tile type
+}
+
+// PaletteTile is one glyph a palette draws with, as the world manifest
+// describes it
+type PaletteTile struct {
+	Glyph    string
+	Char     string
+	Color    string
+	Type     string
+	Walkable bool
+	Opaque   bool // Blocks line of sight
+}
+
+// paletteEntry ties a palette field to the kind of tile it draws
+type paletteEntry struct {
+	Type     string
+	Walkable bool
+	Opaque   bool
+	Glyph    *string
+}
+
+// entries lists every glyph field of the palette, in manifest order
+func (p *Palette) entries() []paletteEntry {
+	return []paletteEntry{
+		{"water", false, false, &p.Water},
+		{"deep_water", false, false, &p.DeepWater},
+		{"grass", true, false, &p.Grass},
+		{"sand", true, false, &p.Sand},
+		{"building", false, true, &p.Building},
+		{"white_building", false, true, &p.WhiteBuilding},
+		{"tree", false, true, &p.Tree},
+		{"pine_tree", false, false, &p.PineTree},
+		{"path", true, false, &p.Path},
+		{"dirt", true, false, &p.Dirt},
+		{"star", true, false, &p.Star},
+		{"marker", true, false, &p.Marker},
+		{"empty", true, false, &p.Empty},
+		{"mountain", false, true, &p.Mountain},
+		{"peak", false, true, &p.Peak},
+		{"snow", true, false, &p.Snow},
+		{"dock", true, false, &p.Dock},
+		{"cobblestone", true, false, &p.Cobblestone},
+		{"bush", false, false, &p.Bush},
+		{"pillar", false, false, &p.Pillar},
+		{"bridge", true, false, &p.Bridge},
+		{"wood_wall", false, true, &p.WoodWall},
+		{"door", true, false, &p.Door},
+		{"window", false, false, &p.Window},
+		{"wood_floor", true, false, &p.WoodFloor},
+		{"chimney", false, false, &p.Chimney},
+		{"shelf", false, false, &p.Shelf},
+		{"table", false, false, &p.Table},
+		{"pedestal", false, false, &p.Pedestal},
+	}
+}
+
+// classicColors are the colors the original world was drawn with
+var classicColors = map[string]string{
+	"water":          "#4da6ff",
+	"deep_water":     "#2d7db3",
+	"grass":          "#90ee90",
+	"sand":           "#f4a460",
+	"building":       "#808080",
+	"white_building": "#f5f5f5",
+	"tree":           "#228b22",
+	"pine_tree":      "#2d5a1d",
+	"path":           "#8b4513",
+	"dirt":           "#9b7653",
+	"star":           "#ffff00",
+	"marker":         "#ff6b6b",
+	"empty":          "#1a1a1a",
+	"mountain":       "#696969",
+	"peak":           "#a9a9a9",
+	"snow":           "#fffafa",
+	"dock":           "#8b7355",
+	"cobblestone":    "#778899",
+	"bush":           "#3cb371",
+	"pillar":         "#dcdcdc",
+	"bridge":         "#cd853f",
+	"wood_wall":      "#4a3728",
+	"door":           "#8b0000",
+	"window":         "#4a90a4",
+	"wood_floor":     "#5c4033",
+	"chimney":        "#654321",
+	"shelf":          "#8b5a2b",
+	"table":          "#a0522d",
+	"pedestal":       "#ffd700",
+}
+
+// paletteThemes are the named palettes a world can be generated with
+var paletteThemes = map[string]func() *Palette{
+	"classic":       DefaultPalette,
+	"emoji-free":    emojiFreePalette,
+	"unicode":       unicodePalette,
+	"high-contrast": highContrastPalette,
+}
+
+// DefaultPaletteName is the theme used when a world doesn't choose one
+const DefaultPaletteName = "classic"
+
+// DefaultPalette returns the standard tile palette
+func DefaultPalette() *Palette {
+	return &Palette{
+		Grass:         "^",
+		Sand:          ".",
+		Water:         "~",
+		DeepWater:     "≈",
+		Snow:          "s",
+		Mountain:      "M",
+		Peak:          "A",
+		Tree:          "T",
+		PineTree:      "t",
+		Bush:          ";",
+		Building:      "#",
+		WhiteBuilding: "B",
+		WoodWall:      "W",
+		Door:          "D",
+		Pillar:        "|",
+		Path:          "+",
+		Dirt:          ",",
+		Cobblestone:   "o",
+		Dock:          "=",
+		Bridge:        "n",
+		Star:          "*",
+		Marker:        "@",
+		Empty:         " ",
+		Window:        "%",
+		WoodFloor:     "░",
+		Chimney:       "H",
+		Shelf:         "≡",
+		Table:         "π",
+		Pedestal:      "¤",
+
+		Name:   "classic",
+		Chars:  map[string]string{"white_building": "#"},
+		Colors: classicColors,
+	}
+}
+
+// emojiFreePalette sticks to printable ASCII, for terminals and fonts without
+// the classic palette's few Unicode glyphs
+func emojiFreePalette() *Palette {
+	p := DefaultPalette()
+	p.Name = "emoji-free"
+	p.DeepWater = "w"
+	p.WoodFloor = "_"
+	p.Shelf = "E"
+	p.Table = "m"
+	p.Pedestal = "$"
+	p.Chars = map[string]string{
+		"white_building": "#",
+		"deep_water":     "~",
+	}
+	return p
+}
+
+// unicodePalette draws with block elements, box drawing and geometric shapes
+func unicodePalette() *Palette {
+	return &Palette{
+		Grass:         "·",
+		Sand:          "∴",
+		Water:         "~",
+		DeepWater:     "≈",
+		Snow:          "∵",
+		Mountain:      "▲",
+		Peak:          "△",
+		Tree:          "♣",
+		PineTree:      "♠",
+		Bush:          "∗",
+		Building:      "█",
+		WhiteBuilding: "▓",
+		WoodWall:      "▒",
+		Door:          "▯",
+		Pillar:        "║",
+		Path:          "░",
+		Dirt:          "∷",
+		Cobblestone:   "▪",
+		Dock:          "═",
+		Bridge:        "╪",
+		Star:          "★",
+		Marker:        "◉",
+		Empty:         " ",
+		Window:        "▢",
+		WoodFloor:     "▫",
+		Chimney:       "▀",
+		Shelf:         "≡",
+		Table:         "π",
+		Pedestal:      "◊",
+
+		Name:   "unicode",
+		Colors: classicColors,
+		Biomes: map[BiomeType]map[string]string{
+			BiomeCastle: {"window": "▣", "pillar": "┃"},
+			BiomeUrban:  {"window": "▤"},
+			BiomeForest: {"wood_floor": "▭"},
+		},
+	}
+}
+
+// highContrastPalette keeps the classic glyphs but with saturated colors on
+// a black background
+func highContrastPalette() *Palette {
+	p := DefaultPalette()
+	p.Name = "high-contrast"
+	p.Colors = map[string]string{
+		"water":          "#00bfff",
+		"deep_water":     "#0040ff",
+		"grass":          "#00ff00",
+		"sand":           "#ffff00",
+		"building":       "#ffffff",
+		"white_building": "#ffffff",
+		"tree":           "#00ff7f",
+		"pine_tree":      "#00c000",
+		"path":           "#ff8000",
+		"dirt":           "#ffa040",
+		"star":           "#ffff00",
+		"marker":         "#ff00ff",
+		"empty":          "#000000",
+		"mountain":       "#c0c0c0",
+		"peak":           "#ffffff",
+		"snow":           "#ffffff",
+		"dock":           "#ffa500",
+		"cobblestone":    "#e0e0e0",
+		"bush":           "#80ff80",
+		"pillar":         "#ffffff",
+		"bridge":         "#ff8000",
+		"wood_wall":      "#ff8c00",
+		"door":           "#ff0000",
+		"window":         "#00ffff",
+		"wood_floor":     "#c08040",
+		"chimney":        "#ff4500",
+		"shelf":          "#ffa500",
+		"table":          "#ffa500",
+		"pedestal":       "#ffff00",
+	}
+	return p
+}
+
+// GetPalette returns the named palette theme, or the default for ""
+func GetPalette(name string) (*Palette, error) {
+	if name == "" {
+		name = DefaultPaletteName
+	}
+	theme, ok := paletteThemes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown palette %q (have %v)", name, PaletteNames())
+	}
+	return theme(), nil
+}
+
+// PaletteNames lists the palette themes in alphabetical order
+func PaletteNames() []string {
+	names := make([]string, 0, len(paletteThemes))
+	for name := range paletteThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithGlyphs returns a copy of the palette with glyphs replaced by tile
+// type, e.g. {"pine_tree": "Y"}
+func (p *Palette) WithGlyphs(glyphs map[string]string) (*Palette, error) {
+	out := *p
+	entries := out.entries()
+
+	// Apply in type order so errors don't depend on map iteration
+	types := make([]string, 0, len(glyphs))
+	for t := range glyphs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		found := false
+		for _, e := range entries {
+			if e.Type == t {
+				*e.Glyph = glyphs[t]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("palette %q has no tile type %q", p.Name, t)
+		}
+	}
+	return &out, nil
+}
+
+// ForBiome returns the palette with the theme's overrides for a biome applied
+func (p *Palette) ForBiome(t BiomeType) (*Palette, error) {
+	if len(p.Biomes[t]) == 0 {
+		return p, nil
+	}
+	return p.WithGlyphs(p.Biomes[t])
+}
+
+// Validate checks that every glyph is a single, non-emoji character and that
+// no glyph stands for two different kinds of tile
+func (p *Palette) Validate() error {
+	seen := make(map[string]string)
+	for _, e := range p.entries() {
+		glyph := *e.Glyph
+		if utf8.RuneCountInString(glyph) != 1 {
+			return fmt.Errorf("palette %q: %s glyph %q must be a single character", p.Name, e.Type, glyph)
+		}
+		if r, _ := utf8.DecodeRuneInString(glyph); isEmoji(r) {
+			return fmt.Errorf("palette %q: %s glyph %q is an emoji", p.Name, e.Type, glyph)
+		}
+		if other, ok := seen[glyph]; ok {
+			return fmt.Errorf("palette %q: glyph %q is used for both %s and %s", p.Name, glyph, other, e.Type)
+		}
+		seen[glyph] = e.Type
+	}
+	return nil
+}
+
+// isEmoji reports whether r is drawn as a (double width) emoji by default
+func isEmoji(r rune) bool {
+	return r >= 0x1F000 || r == 0xFE0F || (r >= 0x2B00 && r <= 0x2BFF)
+}
+
+// Tiles describes every glyph the palette draws with, in manifest order
+func (p *Palette) Tiles() []PaletteTile {
+	entries := p.entries()
+	tiles := make([]PaletteTile, 0, len(entries))
+	for _, e := range entries {
+		char := *e.Glyph
+		if c, ok := p.Chars[e.Type]; ok {
+			char = c
+		}
+		tiles = append(tiles, PaletteTile{
+			Glyph:    *e.Glyph,
+			Char:     char,
+			Color:    p.Colors[e.Type],
+			Type:     e.Type,
+			Walkable: e.Walkable,
+			Opaque:   e.Opaque,
+		})
+	}
+	return tiles
+}
+
+// MergeTiles combines the tiles of several palettes (typically one per
+// chunk) into a single list for the world manifest. A glyph may appear in
+// more than one palette only if it means the same thing in each.
+func MergeTiles(palettes []*Palette) ([]PaletteTile, error) {
+	merged := make([]PaletteTile, 0)
+	index := make(map[string]int)
+	for _, p := range palettes {
+		for _, tile := range p.Tiles() {
+			i, ok := index[tile.Glyph]
+			if !ok {
+				index[tile.Glyph] = len(merged)
+				merged = append(merged, tile)
+				continue
+			}
+			if merged[i] != tile {
+				return nil, fmt.Errorf("glyph %q is %s in one palette and %s in another",
+					tile.Glyph, merged[i].Type, tile.Type)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// ResolvePalette returns the palette a chunk is drawn with: the configured
+// theme, then the theme's overrides for the chunk's biome, then the chunk's
+// own glyph overrides
+func (c *ChunkConfig) ResolvePalette() (*Palette, error) {
+	p, err := GetPalette(c.Palette)
+	if err != nil {
+		return nil, err
+	}
+	if p, err = p.ForBiome(c.Biome); err != nil {
+		return nil, err
+	}
+	if len(c.Glyphs) > 0 {
+		if p, err = p.WithGlyphs(c.Glyphs); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Glyphs lists every glyph a chunk definition uses, including its
+// interiors, in sorted order
+func (d *ChunkDefinition) Glyphs() []string {
+	seen := make(map[string]bool)
+	collect := func(tiles [][]string) {
+		for _, row := range tiles {
+			for _, glyph := range row {
+				seen[glyph] = true
+			}
+		}
+	}
+
+	collect(d.Tiles)
+	for _, interior := range d.Interiors {
+		collect(interior.Tiles)
+	}
+
+	glyphs := make([]string, 0, len(seen))
+	for glyph := range seen {
+		glyphs = append(glyphs, glyph)
+	}
+	sort.Strings(glyphs)
+	return glyphs
+}
@@ -0,0 +1,36 @@
+package generation
+
+import (
+	"testing"
+	"unicode"
+)
+
+// Every named theme resolves to a valid palette called by that name, and
+// the emoji-free theme keeps to printable ASCII
+func TestPaletteThemes(t *testing.T) {
+	for _, name := range PaletteNames() {
+		p, err := GetPalette(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if p.Name != name {
+			t.Errorf("%s: palette calls itself %q", name, p.Name)
+		}
+		if err := p.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	p, err := GetPalette("emoji-free")
+	if err != nil {
+		t.Fatalf("emoji-free: %v", err)
+	}
+	for _, e := range p.entries() {
+		for _, r := range *e.Glyph {
+			if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+				t.Errorf("emoji-free: %s glyph %q is not printable ASCII", e.Type, *e.Glyph)
+			}
+		}
+	}
+}
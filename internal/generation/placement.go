@@ -0,0 +1,246 @@
+package generation
+
+import "fmt"
+
+// placementMargin is the clear space kept around each project structure in a
+// DefaultChunkSize chunk; smaller chunks keep at least one tile
+const placementMargin = 2
+
+// portApproach is how far in from the border a port's approach is kept clear
+// in a DefaultChunkSize chunk (far enough to cover the signpost and its
+// zone); smaller chunks keep minPortApproach
+const (
+	portApproach    = 7
+	minPortApproach = 6
+)
+
+// placementSolver finds positions for project structures that keep clear of
+// terrain, port approaches, the hub plaza and each other
+type placementSolver struct {
+	cg      *ChunkGenerator
+	blocked map[Point]bool // Tiles taken by terrain or reserved for approaches
+	placed  []Bounds       // Structures already placed
+	plaza   Bounds         // Where the hub plaza goes if the center is free
+}
+
+func newPlacementSolver(cg *ChunkGenerator) *placementSolver {
+	s := &placementSolver{
+		cg:      cg,
+		blocked: make(map[Point]bool),
+		placed:  make([]Bounds, 0),
+	}
+
+	// Render the terrain placed so far onto a scratch grid; anything it
+	// touched (water, sand, mountains, passes) is off limits
+	scratch := NewGrid(cg.size, cg.size, cg.biome.BaseTile, cg.biome.BaseWalkable)
+	for _, comp := range cg.components {
+		comp.Render(scratch, cg.palette)
+	}
+	for y := 0; y < cg.size; y++ {
+		for x := 0; x < cg.size; x++ {
+			p := Point{x, y}
+			if scratch.Get(p) != cg.biome.BaseTile || !scratch.IsWalkable(p) {
+				s.blocked[p] = true
+			}
+		}
+	}
+
+	// Keep each port's approach and signpost clear
+	for _, dir := range cg.config.Connections {
+		port := cg.edgePoint(dir, cg.size/2)
+		dx, dy := dir.Opposite().Delta()
+		for i := 0; i < max(cg.rel(portApproach), minPortApproach); i++ {
+			p := port.Add(dx*i, dy*i)
+			for w := -2; w <= 2; w++ {
+				s.blocked[p.Add(w*abs(dy), w*abs(dx))] = true
+			}
+		}
+	}
+
+	center := Point{cg.size / 2, cg.size / 2}
+	s.plaza = NewPlaza(center, cg.plazaRadius(), "square").GetBounds().Expand(1)
+
+	return s
+}
+
+// place finds a position for proj as close to target as the constraints
+// allow and reserves its space
+func (s *placementSolver) place(proj ProjectPlacement, target Point, zone *Zone) (Component, error) {
+	if proj.Pinned != nil {
+		comp := s.build(proj, *proj.Pinned, zone)
+		if reason := s.check(comp); reason != "" {
+			return nil, fmt.Errorf("project %q pinned at (%d,%d) %s",
+				proj.ProjectID, proj.Pinned.X, proj.Pinned.Y, reason)
+		}
+		s.placed = append(s.placed, comp.GetBounds())
+		return comp, nil
+	}
+
+	full := Bounds{0, 0, s.cg.size - 1, s.cg.size - 1}
+	var comp Component
+
+	if proj.Quadrant != "" {
+		area, ok := quadrantBounds(proj.Quadrant, s.cg.size)
+		if !ok {
+			return nil, fmt.Errorf("project %q has unknown quadrant %q", proj.ProjectID, proj.Quadrant)
+		}
+		comp = s.search(proj, area.Center(), area, zone)
+	}
+
+	// A quadrant is only a preference - fall back to anywhere that fits
+	if comp == nil {
+		comp = s.search(proj, target, full, zone)
+	}
+
+	if comp == nil {
+		return nil, fmt.Errorf("no room for project %q (%s, size %d): every position overlaps terrain, a port approach, the hub or another project",
+			proj.ProjectID, proj.Structure, proj.Size)
+	}
+
+	s.placed = append(s.placed, comp.GetBounds())
+	return comp, nil
+}
+
+// search tries every center in area and returns the valid structure closest
+// to target, or nil if none fits. Ties go to the first in scan order.
+func (s *placementSolver) search(proj ProjectPlacement, target Point, area Bounds, zone *Zone) Component {
+	var best Component
+	bestDist := 0
+
+	for y := area.MinY; y <= area.MaxY; y++ {
+		for x := area.MinX; x <= area.MaxX; x++ {
+			pos := Point{x, y}
+			dist := manhattanDist(pos, target)
+			if best != nil && dist >= bestDist {
+				continue
+			}
+
+			comp := s.build(proj, pos, zone)
+			if s.check(comp) == "" {
+				best = comp
+				bestDist = dist
+			}
+		}
+	}
+
+	return best
+}
+
+// build creates proj's structure at pos, choosing entrances unless the
+// placement spells them out
+func (s *placementSolver) build(proj ProjectPlacement, pos Point, zone *Zone) Component {
+	entrances := proj.Entrances
+	if len(entrances) == 0 {
+		// Build once to learn the footprint, then pick doors for it
+		probe := s.cg.buildStructure(proj, pos, nil, zone)
+		entrances = s.chooseEntrances(probe.GetBounds())
+	}
+	return s.cg.buildStructure(proj, pos, entrances, zone)
+}
+
+// chooseEntrances picks door sides for a structure occupying bounds. Each
+// graph neighbour the structure will be routed to gets the usable door
+// closest to it: a structure standing in for the hub connects to every port,
+// anything else connects to the hub at the center. Doors that would open
+// onto terrain, an approach, another structure or the chunk edge are skipped.
+func (s *placementSolver) chooseEntrances(bounds Bounds) []Direction {
+	center := Point{s.cg.size / 2, s.cg.size / 2}
+
+	targets := []Point{center}
+	if bounds.Contains(center) {
+		targets = targets[:0]
+		for _, dir := range s.cg.config.Connections {
+			targets = append(targets, s.cg.edgePoint(dir, s.cg.size/2))
+		}
+	}
+
+	entrances := make([]Direction, 0)
+	for _, target := range targets {
+		best, bestDist := Direction(-1), 0
+		for dir := North; dir <= West; dir++ {
+			anchor := doorAnchors(bounds, []Direction{dir})[0].Position
+			if !s.doorUsable(anchor) {
+				continue
+			}
+			if d := manhattanDist(anchor, target); best < 0 || d < bestDist {
+				best, bestDist = dir, d
+			}
+		}
+		if best >= 0 && !hasDirection(entrances, best) {
+			entrances = append(entrances, best)
+		}
+	}
+
+	// Nothing usable - the placement check will reject this spot anyway
+	if len(entrances) == 0 {
+		return nil
+	}
+	return entrances
+}
+
+// doorUsable reports whether a door anchor at p opens onto free ground
+func (s *placementSolver) doorUsable(p Point) bool {
+	if p.X < 1 || p.Y < 1 || p.X > s.cg.size-2 || p.Y > s.cg.size-2 || s.blocked[p] {
+		return false
+	}
+	for _, other := range s.placed {
+		if other.Expand(1).Contains(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// check returns why comp can't go where it is, or "" if it fits
+func (s *placementSolver) check(comp Component) string {
+	bounds := comp.GetBounds()
+	if bounds.MinX < 1 || bounds.MinY < 1 || bounds.MaxX > s.cg.size-2 || bounds.MaxY > s.cg.size-2 {
+		return "runs off the edge of the chunk"
+	}
+
+	margin := bounds.Expand(max(s.cg.rel(placementMargin), 1))
+	for y := margin.MinY; y <= margin.MaxY; y++ {
+		for x := margin.MinX; x <= margin.MaxX; x++ {
+			if s.blocked[Point{x, y}] {
+				return "overlaps terrain or a port approach"
+			}
+		}
+	}
+
+	for _, anchor := range comp.GetAnchors() {
+		if !s.cg.grid.InBounds(anchor.Position) || s.blocked[anchor.Position] {
+			return "has an entrance that can't be reached"
+		}
+	}
+
+	for _, other := range s.placed {
+		if margin.Overlaps(other) {
+			return "overlaps another project"
+		}
+	}
+
+	// A structure may stand in for the hub by covering the center, but must
+	// otherwise stay off the plaza
+	center := Point{s.cg.size / 2, s.cg.size / 2}
+	if !bounds.Contains(center) && bounds.Overlaps(s.plaza) {
+		return "overlaps the hub plaza"
+	}
+
+	return ""
+}
+
+// quadrantBounds returns the quarter of a chunk of the given size named by q
+func quadrantBounds(q string, size int) (Bounds, bool) {
+	mid := size / 2
+	switch q {
+	case "nw":
+		return Bounds{0, 0, mid - 1, mid - 1}, true
+	case "ne":
+		return Bounds{mid, 0, size - 1, mid - 1}, true
+	case "sw":
+		return Bounds{0, mid, mid - 1, size - 1}, true
+	case "se":
+		return Bounds{mid, mid, size - 1, size - 1}, true
+	}
+	return Bounds{}, false
+}
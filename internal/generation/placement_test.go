@@ -0,0 +1,121 @@
+package generation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// smallWorld is three 40-tile chunks in an L: a grassland hub with a mix of
+// structures, a castle to its east and a town to its south. The town's east
+// exit leads off the map.
+func smallWorld() []ChunkConfig {
+	return []ChunkConfig{
+		{ChunkX: 0, ChunkY: 0, Seed: 11, Size: 40, Biome: BiomeGrassland, Connections: []Direction{East, South},
+			Projects: []ProjectPlacement{
+				{ProjectID: "hall", Name: "The Hall", Structure: "building", Size: 1, Artifacts: []string{"a", "b"}},
+				{ProjectID: "spire", Name: "The Spire", Structure: "tower", Size: 1, Quadrant: "ne"},
+				{ProjectID: "hut", Name: "The Hut", Structure: "cabin", Size: 1},
+				{ProjectID: "yard", Name: "The Yard", Structure: "courtyard", Size: 1},
+			}},
+		{ChunkX: 1, ChunkY: 0, Seed: 12, Size: 40, Biome: BiomeCastle, Connections: []Direction{West},
+			Projects: []ProjectPlacement{
+				{ProjectID: "keep", Name: "The Keep", Structure: "tower", Size: 1, Artifacts: []string{"a", "b", "c"}},
+			}},
+		{ChunkX: 0, ChunkY: 1, Seed: 13, Size: 40, Biome: BiomeUrban, Connections: []Direction{North, East},
+			Projects: []ProjectPlacement{
+				{ProjectID: "shop", Name: "The Shop", Structure: "building", Size: 1},
+				{ProjectID: "shrine", Name: "The Shrine", Structure: "shrine", Size: 1},
+			}},
+	}
+}
+
+// generate builds a chunk, failing the test if it can't
+func generate(t *testing.T, c ChunkConfig) *ChunkDefinition {
+	t.Helper()
+	def, err := NewChunkGenerator(&c).Generate()
+	if err != nil {
+		t.Fatalf("chunk %d,%d: %v", c.ChunkX, c.ChunkY, err)
+	}
+	return def
+}
+
+// structureBounds returns where each project's structure stands: the bounds
+// of its project zone
+func structureBounds(def *ChunkDefinition) map[string]Bounds {
+	out := make(map[string]Bounds)
+	for _, z := range def.Zones {
+		if z.Type == ZoneTypeProject && z.ProjectID != "" {
+			out[z.ProjectID] = Bounds{z.Bounds.MinX, z.Bounds.MinY, z.Bounds.MaxX, z.Bounds.MaxY}
+		}
+	}
+	return out
+}
+
+// The same config and seed always give the same chunk, and a different seed
+// gives a different one
+func TestGenerateDeterministic(t *testing.T) {
+	for _, c := range smallWorld() {
+		first, second := generate(t, c), generate(t, c)
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("chunk %d,%d: two runs on seed %d differ", c.ChunkX, c.ChunkY, c.Seed)
+		}
+
+		c.Seed++
+		if other := generate(t, c); reflect.DeepEqual(first.Tiles, other.Tiles) {
+			t.Errorf("chunk %d,%d: seeds %d and %d give the same tiles", c.ChunkX, c.ChunkY, c.Seed-1, c.Seed)
+		}
+	}
+}
+
+// Every project gets a structure inside the chunk border, and no two come
+// within the placement margin of each other, even when they all want the
+// same corner
+func TestPlacementSpacing(t *testing.T) {
+	crowded := ChunkConfig{ChunkX: 5, ChunkY: 5, Seed: 14, Size: 40, Biome: BiomeForest, Connections: []Direction{North}}
+	for _, id := range []string{"one", "two", "three", "four"} {
+		crowded.Projects = append(crowded.Projects, ProjectPlacement{ProjectID: id, Name: id, Structure: "cabin", Size: 1, Quadrant: "sw"})
+	}
+
+	for _, c := range append(smallWorld(), crowded) {
+		placed := structureBounds(generate(t, c))
+		margin := max(NewChunkGenerator(&c).rel(placementMargin), 1)
+		inside := Bounds{1, 1, c.Size - 2, c.Size - 2}
+
+		for i, a := range c.Projects {
+			ab, ok := placed[a.ProjectID]
+			if !ok {
+				t.Errorf("chunk %d,%d: project %q not placed", c.ChunkX, c.ChunkY, a.ProjectID)
+				continue
+			}
+			if !inside.Contains(Point{ab.MinX, ab.MinY}) || !inside.Contains(Point{ab.MaxX, ab.MaxY}) {
+				t.Errorf("chunk %d,%d: project %q at %+v runs off the chunk", c.ChunkX, c.ChunkY, a.ProjectID, ab)
+			}
+			for _, b := range c.Projects[i+1:] {
+				if bb, ok := placed[b.ProjectID]; ok && ab.Expand(margin).Overlaps(bb) {
+					t.Errorf("chunk %d,%d: %q at %+v and %q at %+v are within %d tiles of each other",
+						c.ChunkX, c.ChunkY, a.ProjectID, ab, b.ProjectID, bb, margin)
+				}
+			}
+		}
+	}
+}
+
+// A pinned project stands exactly where it's pinned, and a pin the solver
+// can't honour is an error rather than a quiet move
+func TestPlacementPinned(t *testing.T) {
+	c := smallWorld()[0]
+	free := structureBounds(generate(t, c))["hut"]
+
+	pin := free.Center()
+	c.Projects[2].Pinned = &pin
+	if got := structureBounds(generate(t, c))["hut"]; got != free {
+		t.Errorf("pinned at %v: got %+v, want %+v", pin, got, free)
+	}
+
+	c.Projects[2].Pinned = &Point{1, 1}
+	_, err := NewChunkGenerator(&c).Generate()
+	if err == nil || !strings.Contains(err.Error(), `project "hut" pinned at (1,1)`) {
+		t.Errorf("pinned off the edge: got error %v, want a pinned project error", err)
+	}
+}
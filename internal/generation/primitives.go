@@ -2,7 +2,9 @@ package generation
 
 import (
 	"container/heap"
+	"hash/fnv"
 	"math"
+	"math/bits"
 )
 
 // Rect fills a rectangular area with a tile
@@ -94,6 +96,31 @@ func (g *Grid) FloodFill(start Point, newTile string, walkable bool) {
 	}
 }
 
+// ReachableFrom returns every walkable tile reachable from start
+func (g *Grid) ReachableFrom(start Point) map[Point]bool {
+	reachable := make(map[Point]bool)
+	queue := []Point{start}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		if reachable[p] || !g.IsWalkable(p) {
+			continue
+		}
+
+		reachable[p] = true
+
+		for _, adj := range p.Adjacent() {
+			if !reachable[adj] {
+				queue = append(queue, adj)
+			}
+		}
+	}
+
+	return reachable
+}
+
 // Scatter randomly places tiles within bounds at a given density
 func (g *Grid) Scatter(b Bounds, tile string, walkable bool, density float64, rng *RNG, avoid map[Point]bool) {
 	for y := b.MinY; y <= b.MaxY; y++ {
@@ -123,13 +150,27 @@ func (g *Grid) ScatterOnTile(b Bounds, targetTile, newTile string, walkable bool
 
 // ---- A* Pathfinding ----
 
+// CostFunc returns the cost of stepping onto a tile. Costs must be at least
+// 1 so the distance heuristic stays admissible; a negative cost means the
+// tile can't be entered at all.
+type CostFunc func(p Point) float64
+
+// PathOptions configures a cost-based path search
+type PathOptions struct {
+	Cost     CostFunc // Step cost; nil costs 1 per walkable tile
+	Diagonal bool     // Allow diagonal steps (expanded to 4-connected on output)
+	Smooth   bool     // Straighten staircase runs where it costs no more
+}
+
+// diagonalCost scales a step's cost when it moves diagonally
+const diagonalCost = math.Sqrt2
+
 // astarNode represents a node in the A* priority queue
 type astarNode struct {
-	point    Point
-	gScore   float64 // Cost from start
-	fScore   float64 // gScore + heuristic
-	parent   *Point
-	index    int
+	point  Point
+	gScore float64 // Cost from start
+	fScore float64 // gScore + heuristic
+	index  int
 }
 
 // priorityQueue implements heap.Interface for A*
@@ -159,72 +200,118 @@ func (pq *priorityQueue) Pop() interface{} {
 // walkableOverride allows treating certain non-walkable tiles as walkable (for path carving)
 // Returns nil if no path found
 func (g *Grid) FindPath(from, to Point, walkableOverride map[Point]bool) []Point {
-	if !g.InBounds(from) || !g.InBounds(to) {
+	return g.FindPathCost(from, to, PathOptions{
+		Cost: func(p Point) float64 {
+			if walkableOverride[p] || g.IsWalkable(p) {
+				return 1
+			}
+			return -1
+		},
+	})
+}
+
+// FindPathAvoid finds a path while avoiding certain points
+func (g *Grid) FindPathAvoid(from, to Point, avoid map[Point]bool) []Point {
+	return g.FindPathCost(from, to, PathOptions{Cost: g.avoidCost(avoid)})
+}
+
+// FindPathCost finds the cheapest path between two points under the given
+// options. The destination can always be entered, so paths can end on a
+// door or anchor. Returns nil if no path found.
+func (g *Grid) FindPathCost(from, to Point, opts PathOptions) []Point {
+	if !g.InBounds(to) {
+		return nil
+	}
+	estimate := func(p Point) float64 { return heuristic(p, to) }
+	if opts.Diagonal {
+		estimate = func(p Point) float64 { return octile(p, to) }
+	}
+	return g.search(from, func(p Point) bool { return p == to }, estimate, opts)
+}
+
+// FindPathToAny finds the cheapest path from a point to whichever of the
+// target points is closest. Returns nil if none of the targets can be reached.
+func (g *Grid) FindPathToAny(from Point, targets map[Point]bool, opts PathOptions) []Point {
+	if len(targets) == 0 {
+		return nil
+	}
+	none := func(Point) float64 { return 0 }
+	return g.search(from, func(p Point) bool { return targets[p] }, none, opts)
+}
+
+// avoidCost costs 1 per walkable tile, treating avoided tiles as blocked
+func (g *Grid) avoidCost(avoid map[Point]bool) CostFunc {
+	return func(p Point) float64 {
+		if avoid[p] || !g.IsWalkable(p) {
+			return -1
+		}
+		return 1
+	}
+}
+
+// search runs A* from a point until it pops a goal tile
+func (g *Grid) search(from Point, goal func(Point) bool, estimate func(Point) float64, opts PathOptions) []Point {
+	if !g.InBounds(from) {
 		return nil
 	}
 
-	isWalkable := func(p Point) bool {
-		if walkableOverride != nil && walkableOverride[p] {
-			return true
+	cost := opts.Cost
+	if cost == nil {
+		cost = g.avoidCost(nil)
+	}
+	stepCost := func(p Point) float64 {
+		if goal(p) {
+			return math.Max(cost(p), 1)
 		}
-		return g.IsWalkable(p)
+		return cost(p)
 	}
 
-	// A* implementation
 	openSet := &priorityQueue{}
 	heap.Init(openSet)
 
-	gScore := make(map[Point]float64)
+	gScore := map[Point]float64{from: 0}
 	cameFrom := make(map[Point]Point)
-	inOpen := make(map[Point]bool)
+	closed := make(map[Point]bool)
 
-	gScore[from] = 0
-	startNode := &astarNode{
-		point:  from,
-		gScore: 0,
-		fScore: heuristic(from, to),
-	}
-	heap.Push(openSet, startNode)
-	inOpen[from] = true
+	heap.Push(openSet, &astarNode{point: from, gScore: 0, fScore: estimate(from)})
 
 	for openSet.Len() > 0 {
 		current := heap.Pop(openSet).(*astarNode)
-		delete(inOpen, current.point)
+		if closed[current.point] {
+			continue // Stale queue entry
+		}
+		closed[current.point] = true
 
-		if current.point == to {
-			// Reconstruct path
-			path := []Point{to}
-			curr := to
-			for curr != from {
+		if goal(current.point) {
+			path := []Point{current.point}
+			for curr := current.point; curr != from; {
 				curr = cameFrom[curr]
 				path = append([]Point{curr}, path...)
 			}
+			if opts.Diagonal {
+				path = g.expandDiagonals(path, stepCost)
+			}
+			if opts.Smooth {
+				path = smoothPath(path, stepCost)
+			}
 			return path
 		}
 
-		for _, neighbor := range current.point.Adjacent() {
-			if !g.InBounds(neighbor) {
-				continue
-			}
-			if !isWalkable(neighbor) && neighbor != to {
-				continue
+		for _, step := range g.steps(current.point, opts.Diagonal, stepCost) {
+			c := stepCost(step)
+			if step.X != current.point.X && step.Y != current.point.Y {
+				c *= diagonalCost
 			}
 
-			tentativeG := gScore[current.point] + 1
-
-			if oldG, exists := gScore[neighbor]; !exists || tentativeG < oldG {
-				cameFrom[neighbor] = current.point
-				gScore[neighbor] = tentativeG
-				fScore := tentativeG + heuristic(neighbor, to)
-
-				if !inOpen[neighbor] {
-					heap.Push(openSet, &astarNode{
-						point:  neighbor,
-						gScore: tentativeG,
-						fScore: fScore,
-					})
-					inOpen[neighbor] = true
-				}
+			tentativeG := current.gScore + c
+			if oldG, exists := gScore[step]; !exists || tentativeG < oldG {
+				cameFrom[step] = current.point
+				gScore[step] = tentativeG
+				heap.Push(openSet, &astarNode{
+					point:  step,
+					gScore: tentativeG,
+					fScore: tentativeG + estimate(step),
+				})
 			}
 		}
 	}
@@ -232,73 +319,137 @@ func (g *Grid) FindPath(from, to Point, walkableOverride map[Point]bool) []Point
 	return nil // No path found
 }
 
-// FindPathAvoid finds a path while avoiding certain points
-func (g *Grid) FindPathAvoid(from, to Point, avoid map[Point]bool) []Point {
-	if !g.InBounds(from) || !g.InBounds(to) {
-		return nil
-	}
+// steps returns the neighbours of p that can be entered. Diagonal steps
+// can't cut corners: both tiles beside the diagonal must be enterable.
+func (g *Grid) steps(p Point, diagonal bool, cost CostFunc) []Point {
+	enterable := func(q Point) bool { return g.InBounds(q) && cost(q) >= 0 }
 
-	isWalkable := func(p Point) bool {
-		if avoid != nil && avoid[p] {
-			return false
+	steps := make([]Point, 0, 8)
+	for _, adj := range p.Adjacent() {
+		if enterable(adj) {
+			steps = append(steps, adj)
 		}
-		return g.IsWalkable(p)
+	}
+	if !diagonal {
+		return steps
 	}
 
-	// Same A* but with avoid check
-	openSet := &priorityQueue{}
-	heap.Init(openSet)
+	for _, d := range [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}} {
+		q := p.Add(d[0], d[1])
+		if enterable(q) && enterable(p.Add(d[0], 0)) && enterable(p.Add(0, d[1])) {
+			steps = append(steps, q)
+		}
+	}
+	return steps
+}
 
-	gScore := make(map[Point]float64)
-	cameFrom := make(map[Point]Point)
-	inOpen := make(map[Point]bool)
+// expandDiagonals turns each diagonal step into two orthogonal ones through
+// the cheaper corner, so the path can be walked 4-directionally
+func (g *Grid) expandDiagonals(path []Point, cost CostFunc) []Point {
+	out := []Point{path[0]}
+	for i := 1; i < len(path); i++ {
+		prev, next := path[i-1], path[i]
+		if prev.X != next.X && prev.Y != next.Y {
+			a, b := Point{next.X, prev.Y}, Point{prev.X, next.Y}
+			if cost(b) < cost(a) {
+				a = b
+			}
+			out = append(out, a)
+		}
+		out = append(out, next)
+	}
+	return out
+}
 
-	gScore[from] = 0
-	heap.Push(openSet, &astarNode{point: from, gScore: 0, fScore: heuristic(from, to)})
-	inOpen[from] = true
+// smoothPath straightens staircase runs. From each point it looks for the
+// furthest later point that can be reached by a single L-shaped turn no
+// more expensive than the stretch of path it replaces.
+func smoothPath(path []Point, cost CostFunc) []Point {
+	if len(path) < 4 {
+		return path
+	}
 
-	for openSet.Len() > 0 {
-		current := heap.Pop(openSet).(*astarNode)
-		delete(inOpen, current.point)
+	// Running cost along the path, so any stretch can be priced in O(1)
+	along := make([]float64, len(path))
+	for i := 1; i < len(path); i++ {
+		along[i] = along[i-1] + cost(path[i])
+	}
 
-		if current.point == to {
-			path := []Point{to}
-			curr := to
-			for curr != from {
-				curr = cameFrom[curr]
-				path = append([]Point{curr}, path...)
+	out := []Point{path[0]}
+	for i := 0; i < len(path)-1; {
+		next := i + 1
+		var shortcut []Point
+		for j := len(path) - 1; j > i+2; j-- {
+			// Only same-length detours, so the path never gets longer
+			if manhattanDist(path[i], path[j]) != j-i {
+				continue
+			}
+			horizontal := path[i+1].Y == path[i].Y
+			if leg := elbow(path[i], path[j], horizontal, along[j]-along[i], cost); leg != nil {
+				next, shortcut = j, leg
+				break
 			}
-			return path
 		}
 
-		for _, neighbor := range current.point.Adjacent() {
-			if !g.InBounds(neighbor) || (!isWalkable(neighbor) && neighbor != to) {
-				continue
-			}
+		if shortcut != nil {
+			out = append(out, shortcut...)
+		} else {
+			out = append(out, path[next])
+		}
+		i = next
+	}
+	return out
+}
 
-			tentativeG := gScore[current.point] + 1
-			if oldG, exists := gScore[neighbor]; !exists || tentativeG < oldG {
-				cameFrom[neighbor] = current.point
-				gScore[neighbor] = tentativeG
-				if !inOpen[neighbor] {
-					heap.Push(openSet, &astarNode{
-						point:  neighbor,
-						gScore: tentativeG,
-						fScore: tentativeG + heuristic(neighbor, to),
-					})
-					inOpen[neighbor] = true
-				}
+// elbow returns the tiles after a on an L-shaped route to b, if one can be
+// entered throughout and costs no more than budget. The turn that keeps the
+// path's original heading (horizontal or vertical) is tried first.
+func elbow(a, b Point, horizontal bool, budget float64, cost CostFunc) []Point {
+	corners := []Point{{b.X, a.Y}, {a.X, b.Y}}
+	if !horizontal {
+		corners[0], corners[1] = corners[1], corners[0]
+	}
+	for _, corner := range corners {
+		leg := append(straightLine(a, corner), straightLine(corner, b)...)
+		total := 0.0
+		ok := true
+		for _, p := range leg {
+			c := cost(p)
+			if c < 0 {
+				ok = false
+				break
 			}
+			total += c
+		}
+		if ok && total <= budget {
+			return leg
 		}
 	}
-
 	return nil
 }
 
+// straightLine returns the tiles after a up to and including b along one axis
+func straightLine(a, b Point) []Point {
+	line := make([]Point, 0, manhattanDist(a, b))
+	dx, dy := sign(b.X-a.X), sign(b.Y-a.Y)
+	for p := a; p != b; {
+		p = p.Add(dx, dy)
+		line = append(line, p)
+	}
+	return line
+}
+
 func heuristic(a, b Point) float64 {
 	return math.Abs(float64(a.X-b.X)) + math.Abs(float64(a.Y-b.Y))
 }
 
+// octile is the distance heuristic when diagonal steps are allowed
+func octile(a, b Point) float64 {
+	dx := math.Abs(float64(a.X - b.X))
+	dy := math.Abs(float64(a.Y - b.Y))
+	return dx + dy + (diagonalCost-2)*math.Min(dx, dy)
+}
+
 func abs(x int) int {
 	if x < 0 {
 		return -x
@@ -306,23 +457,93 @@ func abs(x int) int {
 	return x
 }
 
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
 // ---- Seeded RNG ----
 
-// RNG is a simple seeded random number generator (LCG)
+// RNG is a seeded random number generator running xoshiro256**. An RNG made
+// with NewLegacyRNG runs the original LCG instead, giving the same draws as
+// before the switch for a seed.
 type RNG struct {
-	state uint64
+	s      [4]uint64
+	seed   uint64
+	legacy bool
+	onDraw func(uint64) // Optional hook, used by the trace recorder
 }
 
 // NewRNG creates a new RNG with the given seed
 func NewRNG(seed uint64) *RNG {
-	return &RNG{state: seed}
+	r := &RNG{seed: seed}
+	state := seed
+	for i := range r.s {
+		r.s[i] = splitmix64(&state)
+	}
+	return r
+}
+
+// NewLegacyRNG creates an RNG that reproduces the original LCG sequence
+func NewLegacyRNG(seed uint64) *RNG {
+	return &RNG{s: [4]uint64{seed}, seed: seed, legacy: true}
+}
+
+// Split returns an independent stream for the named purpose. Streams are
+// derived from the seed alone, so drawing more from one never shifts the
+// others. A legacy RNG has only the one stream and returns itself.
+func (r *RNG) Split(name string) *RNG {
+	if r.legacy {
+		return r
+	}
+	child := NewRNG(r.seed ^ hashName(name))
+	child.onDraw = r.onDraw
+	return child
+}
+
+// hashName hashes a stream name for mixing into a seed
+func hashName(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return h.Sum64()
 }
 
 // Uint64 returns a pseudo-random uint64
 func (r *RNG) Uint64() uint64 {
-	// LCG parameters from Numerical Recipes
-	r.state = r.state*6364136223846793005 + 1442695040888963407
-	return r.state
+	var v uint64
+	if r.legacy {
+		// LCG parameters from Numerical Recipes
+		r.s[0] = r.s[0]*6364136223846793005 + 1442695040888963407
+		v = r.s[0]
+	} else {
+		v = bits.RotateLeft64(r.s[1]*5, 7) * 9
+		t := r.s[1] << 17
+		r.s[2] ^= r.s[0]
+		r.s[3] ^= r.s[1]
+		r.s[1] ^= r.s[2]
+		r.s[0] ^= r.s[3]
+		r.s[2] ^= t
+		r.s[3] = bits.RotateLeft64(r.s[3], 45)
+	}
+	if r.onDraw != nil {
+		r.onDraw(v)
+	}
+	return v
+}
+
+// splitmix64 advances state and returns the next SplitMix64 output, used to
+// expand a single seed into well-mixed generator state
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
 }
 
 // Float64 returns a pseudo-random float64 in [0, 1)
@@ -335,7 +556,20 @@ func (r *RNG) Intn(n int) int {
 	if n <= 0 {
 		return 0
 	}
-	return int(r.Uint64() % uint64(n))
+	if r.legacy {
+		return int(r.Uint64() % uint64(n))
+	}
+
+	// Lemire's multiply-and-reject, which is unbiased for any n
+	bound := uint64(n)
+	hi, lo := bits.Mul64(r.Uint64(), bound)
+	if lo < bound {
+		threshold := -bound % bound
+		for lo < threshold {
+			hi, lo = bits.Mul64(r.Uint64(), bound)
+		}
+	}
+	return int(hi)
 }
 
 // IntRange returns a pseudo-random int in [min, max]
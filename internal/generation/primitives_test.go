@@ -0,0 +1,273 @@
+package generation
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// The first draws for seed 42, worked out independently from the xoshiro256**
+// and SplitMix64 reference code and the Numerical Recipes LCG. A change here
+// changes every generated world.
+func TestRNGGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		rng  *RNG
+		want []uint64
+	}{
+		{"xoshiro", NewRNG(42), []uint64{
+			1546998764402558742, 6990951692964543102, 12544586762248559009,
+			17057574109182124193, 18295552978065317476,
+		}},
+		{"legacy", NewLegacyRNG(42), []uint64{
+			10481999410520546993, 4159066171780167020, 7615522811268512075,
+			11628791489956661374, 12546512532490043765,
+		}},
+	}
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := tt.rng.Uint64(); got != want {
+				t.Errorf("%s draw %d = %d, want %d", tt.name, i, got, want)
+			}
+		}
+	}
+}
+
+// Intn spreads draws evenly for small n: a chi-square test at p = 0.001.
+// Seeds are fixed, so the test is deterministic.
+func TestIntnUniform(t *testing.T) {
+	// Chi-square critical values at p = 0.001 by degrees of freedom
+	critical := map[int]float64{1: 10.83, 2: 13.82, 4: 18.47, 5: 20.52, 6: 22.46, 9: 27.88}
+
+	for _, n := range []int{2, 3, 5, 6, 7, 10} {
+		const perBucket = 10000
+		rng := NewRNG(uint64(n))
+		counts := make([]int, n)
+		for i := 0; i < n*perBucket; i++ {
+			v := rng.Intn(n)
+			if v < 0 || v >= n {
+				t.Fatalf("Intn(%d) = %d", n, v)
+			}
+			counts[v]++
+		}
+
+		chi := 0.0
+		for _, c := range counts {
+			d := float64(c - perBucket)
+			chi += d * d / perBucket
+		}
+		if chi > critical[n-1] {
+			t.Errorf("Intn(%d): chi-square %.2f exceeds %.2f, counts %v", n, chi, critical[n-1], counts)
+		}
+	}
+}
+
+// Split streams depend only on the seed and their name: drawing from one
+// doesn't move another, and their outputs are uncorrelated
+func TestSplitIndependent(t *testing.T) {
+	const draws = 10000
+	root := NewRNG(7)
+	a, b := root.Split("a"), root.Split("b")
+
+	// Drawing from a and the root first mustn't shift b
+	for i := 0; i < 100; i++ {
+		a.Uint64()
+		root.Uint64()
+	}
+	fresh := NewRNG(7).Split("b")
+	for i := 0; i < 100; i++ {
+		if got, want := b.Uint64(), fresh.Uint64(); got != want {
+			t.Fatalf("stream b draw %d = %d after drawing from a, want %d", i, got, want)
+		}
+	}
+
+	// Pearson correlation between the streams' floats
+	var sumA, sumB, sumAA, sumBB, sumAB float64
+	for i := 0; i < draws; i++ {
+		x, y := a.Float64(), b.Float64()
+		sumA += x
+		sumB += y
+		sumAA += x * x
+		sumBB += y * y
+		sumAB += x * y
+	}
+	cov := sumAB/draws - sumA/draws*sumB/draws
+	varA := sumAA/draws - sumA/draws*sumA/draws
+	varB := sumBB/draws - sumB/draws*sumB/draws
+	if r := cov / math.Sqrt(varA*varB); math.Abs(r) > 0.05 {
+		t.Errorf("streams a and b correlate: r = %.3f", r)
+	}
+
+	// Same name, same stream; the root's seed decides, not its position
+	c, d := NewRNG(7).Split("a"), NewRNG(7).Split("a")
+	for i := 0; i < 10; i++ {
+		if c.Uint64() != d.Uint64() {
+			t.Fatal("splitting the same name twice gave different streams")
+		}
+	}
+}
+
+// obstacleGrid returns a size by size grid crossed by walls every sixth
+// column, each with a gap at a random row, and boulders scattered between
+// them. Corner to corner has to weave through the gaps.
+func obstacleGrid(size int) *Grid {
+	g := NewGrid(size, size, ".", true)
+	rng := NewRNG(1)
+	for x := 3; x < size-1; x += 6 {
+		gap := rng.Intn(size)
+		for y := 0; y < size; y++ {
+			if y != gap {
+				g.Set(Point{x, y}, "#", false)
+			}
+		}
+		// Boulders in the columns between walls, never next to a gap
+		for y := 0; y < size; y++ {
+			if abs(y-gap) > 1 && rng.Intn(8) == 0 {
+				g.Set(Point{x + 1 + rng.Intn(2), y}, "^", false)
+			}
+		}
+	}
+	return g
+}
+
+func BenchmarkFindPathCost(b *testing.B) {
+	options := []struct {
+		name string
+		opts PathOptions
+	}{
+		{"plain", PathOptions{}},
+		{"diagonal", PathOptions{Diagonal: true}},
+		{"smooth", PathOptions{Smooth: true}},
+		{"diagonal+smooth", PathOptions{Diagonal: true, Smooth: true}},
+	}
+
+	for _, size := range []int{50, 100, 200} {
+		g := obstacleGrid(size)
+		from, to := Point{0, 0}, Point{size - 1, size - 1}
+		for _, o := range options {
+			b.Run(fmt.Sprintf("%dx%d/%s", size, size, o.name), func(b *testing.B) {
+				if g.FindPathCost(from, to, o.opts) == nil {
+					b.Fatal("no path across the grid")
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					g.FindPathCost(from, to, o.opts)
+				}
+			})
+		}
+	}
+}
+
+// checkPath fails the test unless path runs from from to to in orthogonal
+// steps over tiles cost allows, returning what it costs
+func checkPath(t *testing.T, name string, path []Point, from, to Point, cost CostFunc) float64 {
+	t.Helper()
+	if len(path) == 0 || path[0] != from || path[len(path)-1] != to {
+		t.Fatalf("%s: path %v doesn't run from %v to %v", name, path, from, to)
+	}
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		if manhattanDist(path[i-1], path[i]) != 1 {
+			t.Fatalf("%s: step %d goes from %v to %v", name, i, path[i-1], path[i])
+		}
+		c := cost(path[i])
+		if c < 0 {
+			t.Fatalf("%s: step %d enters blocked tile %v", name, i, path[i])
+		}
+		total += c
+	}
+	return total
+}
+
+// A* takes the cheaper route, not the shorter one: round a costly marsh
+// when that's cheaper, through it when it isn't
+func TestFindPathCostPrefersCheaper(t *testing.T) {
+	// A marsh across the middle three rows of column 3
+	g := NewGrid(7, 5, ".", true)
+	for y := 1; y <= 3; y++ {
+		g.Set(Point{3, y}, "~", true)
+	}
+	from, to := Point{0, 2}, Point{6, 2}
+
+	tests := []struct {
+		marsh     float64
+		wantCost  float64
+		wantSteps int
+	}{
+		{10, 10, 10}, // Round by row 0 or 4: four steps more, each costing 1
+		{2, 7, 6},    // Straight through: one step costing 2
+	}
+	for _, tt := range tests {
+		cost := func(p Point) float64 {
+			if g.Get(p) == "~" {
+				return tt.marsh
+			}
+			return 1
+		}
+		path := g.FindPathCost(from, to, PathOptions{Cost: cost})
+		name := fmt.Sprintf("marsh %v", tt.marsh)
+		if got := checkPath(t, name, path, from, to, cost); got != tt.wantCost || len(path)-1 != tt.wantSteps {
+			t.Errorf("%s: path %v costs %v in %d steps, want %v in %d", name, path, got, len(path)-1, tt.wantCost, tt.wantSteps)
+		}
+	}
+}
+
+// Diagonal steps never squeeze between two tiles that touch corners, and
+// come out as orthogonal steps round the open side
+func TestFindPathCostDiagonalCorners(t *testing.T) {
+	// Walls at (1,0) and (0,1) seal (0,0) off but for the diagonal
+	g := NewGrid(3, 3, ".", true)
+	g.Set(Point{1, 0}, "#", false)
+	g.Set(Point{0, 1}, "#", false)
+	if path := g.FindPathCost(Point{0, 0}, Point{1, 1}, PathOptions{Diagonal: true}); path != nil {
+		t.Errorf("path %v squeezes between the walls", path)
+	}
+
+	// With one wall the diagonal goes round the open corner
+	g.Set(Point{0, 1}, ".", true)
+	path := g.FindPathCost(Point{0, 0}, Point{1, 1}, PathOptions{Diagonal: true})
+	want := []Point{{0, 0}, {0, 1}, {1, 1}}
+	if fmt.Sprint(path) != fmt.Sprint(want) {
+		t.Errorf("path %v, want %v", path, want)
+	}
+
+	// Across a cluttered grid every step stays orthogonal and open
+	g = obstacleGrid(50)
+	cost := g.avoidCost(nil)
+	from, to := Point{0, 0}, Point{49, 49}
+	checkPath(t, "diagonal across obstacles", g.FindPathCost(from, to, PathOptions{Diagonal: true}), from, to, cost)
+}
+
+// Smoothing straightens paths without entering blocked tiles, lengthening
+// them or raising their cost
+func TestFindPathCostSmoothStaysWalkable(t *testing.T) {
+	g := obstacleGrid(60)
+	cost := g.avoidCost(nil)
+	rng := NewRNG(3)
+
+	for i := 0; i < 200; i++ {
+		from := Point{rng.Intn(60), rng.Intn(60)}
+		to := Point{rng.Intn(60), rng.Intn(60)}
+		if !g.IsWalkable(from) || !g.IsWalkable(to) {
+			continue
+		}
+		for _, diagonal := range []bool{false, true} {
+			rough := g.FindPathCost(from, to, PathOptions{Diagonal: diagonal})
+			smooth := g.FindPathCost(from, to, PathOptions{Diagonal: diagonal, Smooth: true})
+			if rough == nil || smooth == nil {
+				if (rough == nil) != (smooth == nil) {
+					t.Fatalf("%v to %v: smoothing changed whether there's a path", from, to)
+				}
+				continue
+			}
+
+			name := fmt.Sprintf("%v to %v, diagonal %v", from, to, diagonal)
+			roughCost := checkPath(t, name, rough, from, to, cost)
+			smoothCost := checkPath(t, name+" smoothed", smooth, from, to, cost)
+			if len(smooth) > len(rough) || smoothCost > roughCost {
+				t.Errorf("%s: smoothing took %d steps costing %v to %d costing %v",
+					name, len(rough)-1, roughCost, len(smooth)-1, smoothCost)
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package generation
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RoutingMode selects which connections in the chunk graph become paths
+type RoutingMode int
+
+const (
+	RoutingStar   RoutingMode = iota // Every port and project straight to the hub
+	RoutingMST                       // Minimum spanning tree over all nodes
+	RoutingLoops                     // Spanning tree plus a few short loop edges
+	RoutingMerged                    // Spanning tree, each path joining the nearest existing one
+)
+
+// maxLoopEdges caps how many loop edges RoutingLoops adds to the tree
+const maxLoopEdges = 2
+
+// planRoutes replaces the hub's star of edges with the edges the biome's
+// routing mode calls for. The tree is taken over every pair of nodes, so a
+// project can hang off a port or a neighbouring project rather than the hub.
+func (cg *ChunkGenerator) planRoutes() {
+	if cg.biome.Routing == RoutingStar || len(cg.graph.Nodes) < 3 {
+		return
+	}
+
+	// Candidate edges are added in ID order so ties in the MST break the
+	// same way every run
+	ids := cg.graph.NodeIDs()
+	candidates := NewGraph()
+	for _, id := range ids {
+		candidates.AddNode(cg.graph.Nodes[id])
+	}
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			candidates.AddEdge(ids[i], ids[j], true)
+		}
+	}
+
+	chosen := candidates.MST()
+	if cg.biome.Routing == RoutingLoops {
+		chosen = append(chosen, loopEdges(candidates.Edges, chosen, float64(cg.size/2))...)
+	}
+
+	cg.graph.ClearEdges()
+	for _, e := range chosen {
+		cg.graph.AddEdge(e.From, e.To, e.Required)
+	}
+}
+
+// loopEdges picks up to maxLoopEdges candidate edges no heavier than
+// maxWeight that cut out a long way round the tree. Loops are optional, so
+// they are not required.
+func loopEdges(candidates, tree []*Edge, maxWeight float64) []*Edge {
+	inTree := make(map[*Edge]bool)
+	for _, e := range tree {
+		inTree[e] = true
+	}
+
+	sorted := make([]*Edge, 0, len(candidates))
+	for _, e := range candidates {
+		if !inTree[e] {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Weight < sorted[j].Weight })
+
+	network := append([]*Edge{}, tree...)
+	loops := make([]*Edge, 0, maxLoopEdges)
+	for _, e := range sorted {
+		if len(loops) == maxLoopEdges || e.Weight > maxWeight {
+			break
+		}
+		if routeWeight(network, e.From, e.To) >= 2*e.Weight {
+			e.Required = false
+			loops = append(loops, e)
+			network = append(network, e)
+		}
+	}
+	return loops
+}
+
+// routeMerged grows the path network outward from the first port along the
+// spanning tree. Each new path runs to whichever network tile is nearest, so
+// paths heading the same way share tiles instead of running side by side.
+func (cg *ChunkGenerator) routeMerged(avoid map[Point]bool) error {
+	// Nothing to connect; validate reports the missing connections
+	if len(cg.graph.Nodes) == 0 {
+		return nil
+	}
+
+	root := cg.graph.Nodes[cg.graph.NodeIDs()[0]]
+	for _, id := range cg.graph.NodeIDs() {
+		if cg.graph.Nodes[id].Type == NodeEdgePort {
+			root = cg.graph.Nodes[id]
+			break
+		}
+	}
+
+	network := make(map[Point]bool)
+	cg.joinNetwork(network, root, cg.findClosestAnchor(root, root.Position))
+
+	visited := map[string]bool{root.ID: true}
+	queue := []string{root.ID}
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		parent := cg.graph.Nodes[parentID]
+
+		for _, childID := range cg.graph.Adjacent[parentID] {
+			if visited[childID] {
+				continue
+			}
+			visited[childID] = true
+			queue = append(queue, childID)
+
+			child := cg.graph.Nodes[childID]
+			edge := cg.graph.GetEdge(parentID, childID)
+			from := cg.findClosestAnchor(child, parent.Position)
+
+			path := cg.grid.FindPathToAny(from, network, cg.pathOptions(avoid))
+			if path == nil {
+				path = cg.grid.FindPathToAny(from, network, cg.pathOptions(nil))
+			}
+			for bridges := 0; path == nil && bridges < 3; bridges++ {
+				if !cg.bridgeWater(from, cg.nearestPoint(network, from)) {
+					break
+				}
+				path = cg.grid.FindPathToAny(from, network, cg.pathOptions(avoid))
+				if path == nil {
+					path = cg.grid.FindPathToAny(from, network, cg.pathOptions(nil))
+				}
+			}
+
+			// The network end of the path stands in for the parent's anchor
+			joined := cg.nearestPoint(network, from)
+			if path != nil {
+				joined = path[len(path)-1]
+			}
+			edge.FromAnchor, edge.ToAnchor, edge.Routed = from, joined, true
+			if edge.From == parentID {
+				edge.FromAnchor, edge.ToAnchor = joined, from
+			}
+
+			if path == nil {
+				if edge.Required {
+					return fmt.Errorf("could not route required path from %s to %s", edge.From, edge.To)
+				}
+				continue
+			}
+
+			edge.Path = path
+			cg.drawPath(path)
+			for _, p := range path {
+				network[p] = true
+			}
+			cg.joinNetwork(network, child, from)
+		}
+	}
+
+	return nil
+}
+
+// pathOptions returns the search options for laying a path: smoothed, and
+// costed so routes follow existing paths, keep off sand, snow and the chunk
+// border, and give buildings a little room
+func (cg *ChunkGenerator) pathOptions(avoid map[Point]bool) PathOptions {
+	return PathOptions{Cost: cg.pathCost(avoid), Smooth: true}
+}
+
+// pathCost prices a tile for path routing. Avoided and unwalkable tiles are
+// blocked.
+func (cg *ChunkGenerator) pathCost(avoid map[Point]bool) CostFunc {
+	return func(p Point) float64 {
+		if avoid[p] || !cg.grid.IsWalkable(p) {
+			return -1
+		}
+
+		cost := 2.0
+		switch cg.grid.Get(p) {
+		case cg.biome.PathTile, cg.palette.Path, cg.palette.Cobblestone, cg.palette.Bridge:
+			cost = 1
+		case cg.palette.Sand, cg.palette.Snow:
+			cost = 4
+		}
+
+		if p.X == 0 || p.Y == 0 || p.X == cg.size-1 || p.Y == cg.size-1 {
+			cost += 4
+		}
+		for _, adj := range p.Adjacent() {
+			if cg.isBuilding(adj) {
+				cost += 6
+				break
+			}
+		}
+		return cost
+	}
+}
+
+// isBuilding reports whether a tile is part of a structure's walls
+func (cg *ChunkGenerator) isBuilding(p Point) bool {
+	switch cg.grid.Get(p) {
+	case cg.palette.Building, cg.palette.WhiteBuilding, cg.palette.WoodWall,
+		cg.palette.Window, cg.palette.Chimney, cg.palette.Pillar:
+		return true
+	}
+	return false
+}
+
+// joinNetwork adds a node's usable anchors to the path network. A plaza's
+// anchors are all joined by its paving; any other node only contributes the
+// anchor its path arrived at.
+func (cg *ChunkGenerator) joinNetwork(network map[Point]bool, node *Node, used Point) {
+	network[used] = true
+	if node.Type == NodeHub {
+		for _, a := range node.Anchors {
+			network[a.Position] = true
+		}
+	}
+}
+
+// nearestPoint returns the point in set closest to p, scanning in grid
+// order so ties resolve deterministically
+func (cg *ChunkGenerator) nearestPoint(set map[Point]bool, p Point) Point {
+	best, bestDist := p, -1
+	for y := 0; y < cg.size; y++ {
+		for x := 0; x < cg.size; x++ {
+			q := Point{x, y}
+			if !set[q] {
+				continue
+			}
+			if d := manhattanDist(p, q); bestDist < 0 || d < bestDist {
+				best, bestDist = q, d
+			}
+		}
+	}
+	return best
+}
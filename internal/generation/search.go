@@ -0,0 +1,131 @@
+package generation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// RetryPolicy controls what happens when a chunk fails to generate. Each
+// retry uses an alternate seed derived from the configured one, so a search
+// always visits the same seeds in the same order.
+type RetryPolicy struct {
+	MaxAttempts int  // Seeds to try in total, including the configured one
+	Candidates  int  // Successes to score before keeping the best (0 or 1 keeps the first)
+	Trace       bool // Record a trace for every attempt
+}
+
+// DefaultRetryPolicy tries a handful of seeds and keeps the first success
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 5, Candidates: 1}
+}
+
+// Score rates a generated chunk. Each part is in [0, 1], higher is better.
+type Score struct {
+	PathLength int     `json:"path_length"` // Tiles of path laid
+	Paths      float64 `json:"paths"`       // Shorter networks score higher
+	OpenSpace  float64 `json:"open_space"`  // Fraction of walkable tiles
+	Decoration float64 `json:"decoration"`  // How close tree cover is to the biome's density
+	Total      float64 `json:"total"`
+}
+
+// SearchResult is the outcome of generating a chunk under a retry policy
+type SearchResult struct {
+	Chunk    *ChunkDefinition
+	Seed     uint64 // Seed that produced Chunk
+	Attempts int    // Seeds tried
+	Score    Score
+	Trace    *Trace  // Trace of the kept attempt, or the last failure
+	Failures []error // Why each failed attempt failed, in order
+}
+
+// GenerateWithRetry generates a chunk, retrying with alternate seeds when
+// generation fails. With Candidates above one it keeps searching after the
+// first success and returns the best-scoring chunk found.
+func GenerateWithRetry(config ChunkConfig, policy RetryPolicy) (*SearchResult, error) {
+	attempts := max(policy.MaxAttempts, 1)
+	wanted := max(policy.Candidates, 1)
+
+	result := &SearchResult{}
+	found := 0
+	for i := 0; i < attempts && found < wanted; i++ {
+		cfg := config
+		cfg.Seed = AttemptSeed(config.Seed, i)
+
+		gen := NewChunkGenerator(&cfg)
+		if policy.Trace {
+			gen.EnableTrace()
+		}
+		result.Attempts++
+
+		chunk, err := gen.Generate()
+		if err != nil {
+			result.Failures = append(result.Failures, fmt.Errorf("seed %d: %w", cfg.Seed, err))
+			if found == 0 {
+				result.Trace = gen.Trace()
+			}
+			continue
+		}
+
+		score := gen.Score()
+		if found == 0 || score.Total > result.Score.Total {
+			result.Chunk = chunk
+			result.Seed = cfg.Seed
+			result.Score = score
+			result.Trace = gen.Trace()
+		}
+		found++
+	}
+
+	if found == 0 {
+		return result, fmt.Errorf("no seed succeeded in %d attempts: %w", result.Attempts, errors.Join(result.Failures...))
+	}
+	return result, nil
+}
+
+// AttemptSeed returns the seed for the given attempt. Attempt 0 is the
+// configured seed; later ones are scrambled with SplitMix64 so neighbouring
+// attempts don't produce near-identical chunks.
+func AttemptSeed(seed uint64, attempt int) uint64 {
+	if attempt == 0 {
+		return seed
+	}
+	state := seed + uint64(attempt-1)*0x9e3779b97f4a7c15
+	return splitmix64(&state)
+}
+
+// Score rates the chunk produced by the last successful Generate
+func (cg *ChunkGenerator) Score() Score {
+	var s Score
+	for _, e := range cg.graph.Edges {
+		s.PathLength += len(e.Path)
+	}
+
+	walkable, trees := 0, 0
+	for y := 0; y < cg.size; y++ {
+		for x := 0; x < cg.size; x++ {
+			p := Point{x, y}
+			if cg.grid.IsWalkable(p) {
+				walkable++
+			}
+			if cg.grid.Get(p) == cg.biome.TreeType {
+				trees++
+			}
+		}
+	}
+	area := float64(cg.size * cg.size)
+
+	// A network as long as a quarter of the chunk's area scores zero
+	s.Paths = clamp01(1 - float64(s.PathLength)/(area/4))
+	s.OpenSpace = float64(walkable) / area
+	target := math.Max(cg.biome.TreeDensity, 0.01)
+	s.Decoration = clamp01(1 - math.Abs(float64(trees)/area-target)/target)
+	s.Total = (s.Paths + s.OpenSpace + s.Decoration) / 3
+
+	return s
+}
+
+// clamp01 limits x to [0, 1]
+func clamp01(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
@@ -0,0 +1,173 @@
+package generation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSignProjects is how many projects beyond the next chunk a sign lists
+const maxSignProjects = 3
+
+// Destination is what a signpost says about where an exit leads
+type Destination struct {
+	Name  string   // The neighbouring chunk
+	Lines []string // What the sign reads, one line each
+}
+
+// ChunkCoord identifies a chunk in the world grid
+type ChunkCoord struct {
+	X, Y int
+}
+
+// AtlasProject is what signposts know about a project
+type AtlasProject struct {
+	Title    string
+	Featured bool // Worth pointing to from further away
+}
+
+// WorldAtlas is the world knowledge signposts are written from: chunk names
+// (from the world manifest) and project details (from the project catalogue)
+type WorldAtlas struct {
+	Names    map[ChunkCoord]string
+	Projects map[string]AtlasProject
+}
+
+// Destinations works out the sign at every exit of every chunk: where the
+// exit leads, what's there, and which featured projects further on are
+// reached fastest through it. Hints from the configs end each sign.
+func (a WorldAtlas) Destinations(configs []ChunkConfig) map[ChunkCoord]map[Direction]Destination {
+	chunks := make(map[ChunkCoord]*ChunkConfig, len(configs))
+	for i := range configs {
+		c := &configs[i]
+		chunks[ChunkCoord{c.ChunkX, c.ChunkY}] = c
+	}
+
+	out := make(map[ChunkCoord]map[Direction]Destination, len(configs))
+	for i := range configs {
+		c := &configs[i]
+		here := ChunkCoord{c.ChunkX, c.ChunkY}
+		out[here] = make(map[Direction]Destination)
+
+		// Distances from each neighbour, not passing back through here
+		reach := make(map[Direction]map[ChunkCoord]int)
+		for _, dir := range c.Connections {
+			next := here.Step(dir)
+			if chunks[next] != nil {
+				reach[dir] = chunkDistances(chunks, next, here)
+			}
+		}
+
+		// Each project is signposted from the exit that reaches it soonest
+		beyond := make(map[Direction][]signTarget)
+		for coord, other := range chunks {
+			if coord == here {
+				continue
+			}
+			best, bestDist := Direction(-1), 0
+			for _, dir := range c.Connections {
+				if d, ok := reach[dir][coord]; ok && (best < 0 || d < bestDist || (d == bestDist && dir < best)) {
+					best, bestDist = dir, d
+				}
+			}
+			if best < 0 || bestDist == 0 {
+				continue // Unreachable, or the neighbour itself
+			}
+			for _, proj := range other.Projects {
+				if a.Projects[proj.ProjectID].Featured {
+					beyond[best] = append(beyond[best], signTarget{a.title(proj), bestDist + 1})
+				}
+			}
+		}
+
+		for _, dir := range c.Connections {
+			next := here.Step(dir)
+			neighbour := chunks[next]
+			if neighbour == nil {
+				continue
+			}
+
+			name := a.Names[next]
+			if name == "" {
+				name = fmt.Sprintf("the %s chunk (%d, %d)", neighbour.Biome, next.X, next.Y)
+			}
+
+			heading := dir.String()
+			lines := []string{fmt.Sprintf("%s: %s", strings.ToUpper(heading[:1])+heading[1:], name)}
+			if len(neighbour.Projects) > 0 {
+				titles := make([]string, len(neighbour.Projects))
+				for i, proj := range neighbour.Projects {
+					titles[i] = a.title(proj)
+				}
+				lines = append(lines, "There: "+strings.Join(titles, ", "))
+			}
+
+			targets := beyond[dir]
+			sort.SliceStable(targets, func(i, j int) bool {
+				if targets[i].dist != targets[j].dist {
+					return targets[i].dist < targets[j].dist
+				}
+				return targets[i].title < targets[j].title
+			})
+			if len(targets) > maxSignProjects {
+				targets = targets[:maxSignProjects]
+			}
+			if len(targets) > 0 {
+				further := make([]string, len(targets))
+				for i, t := range targets {
+					further[i] = fmt.Sprintf("%s (%d chunks)", t.title, t.dist)
+				}
+				lines = append(lines, "Further: "+strings.Join(further, ", "))
+			}
+
+			if hint := c.SignpostHints[dir]; hint != "" {
+				lines = append(lines, hint)
+			}
+
+			out[here][dir] = Destination{Name: name, Lines: lines}
+		}
+	}
+
+	return out
+}
+
+// signTarget is a project a sign points to and how many chunks away it is
+type signTarget struct {
+	title string
+	dist  int
+}
+
+// title names a project on a sign: its catalogue title if it has one,
+// otherwise its in-world name
+func (a WorldAtlas) title(proj ProjectPlacement) string {
+	if p, ok := a.Projects[proj.ProjectID]; ok && p.Title != "" {
+		return p.Title
+	}
+	return proj.Name
+}
+
+// Step returns the neighbouring chunk in a direction
+func (c ChunkCoord) Step(dir Direction) ChunkCoord {
+	dx, dy := dir.Delta()
+	return ChunkCoord{c.X + dx, c.Y + dy}
+}
+
+// chunkDistances walks the chunk connections breadth-first from start,
+// without entering skip, and returns how many steps each chunk is from start
+func chunkDistances(chunks map[ChunkCoord]*ChunkConfig, start, skip ChunkCoord) map[ChunkCoord]int {
+	dist := map[ChunkCoord]int{start: 0}
+	queue := []ChunkCoord{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dir := range chunks[current].Connections {
+			next := current.Step(dir)
+			if _, seen := dist[next]; seen || next == skip || chunks[next] == nil {
+				continue
+			}
+			dist[next] = dist[current] + 1
+			queue = append(queue, next)
+		}
+	}
+	return dist
+}
@@ -0,0 +1,108 @@
+package generation
+
+import (
+	"reflect"
+	"testing"
+)
+
+// smallAtlas names two of smallWorld's chunks and features one project at
+// each end of the L
+func smallAtlas() WorldAtlas {
+	return WorldAtlas{
+		Names: map[ChunkCoord]string{{0, 0}: "the Green", {1, 0}: "Castle Hill"},
+		Projects: map[string]AtlasProject{
+			"keep": {Title: "Keep Project", Featured: true},
+			"shop": {Featured: true},
+		},
+	}
+}
+
+// Each exit's sign names the chunk it leads to and what's there, points past
+// it to featured projects further on, and ends with the config's hint. An
+// exit leading off the map gets no sign.
+func TestDestinations(t *testing.T) {
+	configs := smallWorld()
+	configs[2].SignpostHints = map[Direction]string{North: "Mind the gate."}
+	hub := "There: The Hall, The Spire, The Hut, The Yard"
+
+	want := map[ChunkCoord]map[Direction]Destination{
+		{0, 0}: {
+			East:  {"Castle Hill", []string{"East: Castle Hill", "There: Keep Project"}},
+			South: {"the urban chunk (0, 1)", []string{"South: the urban chunk (0, 1)", "There: The Shop, The Shrine"}},
+		},
+		{1, 0}: {
+			West: {"the Green", []string{"West: the Green", hub, "Further: The Shop (2 chunks)"}},
+		},
+		{0, 1}: {
+			North: {"the Green", []string{"North: the Green", hub, "Further: Keep Project (2 chunks)", "Mind the gate."}},
+		},
+	}
+
+	got := smallAtlas().Destinations(configs)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if again := smallAtlas().Destinations(configs); !reflect.DeepEqual(got, again) {
+		t.Errorf("two runs differ: %v and %v", got, again)
+	}
+}
+
+// Every sign points through one of its chunk's exits at a chunk that exists
+func TestDestinationsPointAtChunks(t *testing.T) {
+	configs := smallWorld()
+	chunks := make(map[ChunkCoord]ChunkConfig)
+	for _, c := range configs {
+		chunks[ChunkCoord{c.ChunkX, c.ChunkY}] = c
+	}
+
+	for here, signs := range smallAtlas().Destinations(configs) {
+		for dir := range signs {
+			connected := false
+			for _, d := range chunks[here].Connections {
+				connected = connected || d == dir
+			}
+			if !connected {
+				t.Errorf("chunk %v: sign facing %s, which has no exit", here, dir)
+			}
+			if _, ok := chunks[here.Step(dir)]; !ok {
+				t.Errorf("chunk %v: sign facing %s points at missing chunk %v", here, dir, here.Step(dir))
+			}
+		}
+	}
+}
+
+// A generated chunk puts a marked signpost on each exit, named for where the
+// exit leads when the atlas knows
+func TestGeneratedSignposts(t *testing.T) {
+	configs := smallWorld()
+	dests := smallAtlas().Destinations(configs)
+
+	for _, c := range configs {
+		c.Destinations = dests[ChunkCoord{c.ChunkX, c.ChunkY}]
+		def := generate(t, c)
+
+		names := make(map[string]bool)
+		for _, z := range def.Zones {
+			if z.Type != ZoneTypeSignpost {
+				continue
+			}
+			names[z.Name] = true
+			x, y := (z.Bounds.MinX+z.Bounds.MaxX)/2, (z.Bounds.MinY+z.Bounds.MaxY)/2
+			if got := def.Tiles[y][x]; got != DefaultPalette().Marker {
+				t.Errorf("chunk %d,%d: %q stands on %q, want the marker", c.ChunkX, c.ChunkY, z.Name, got)
+			}
+		}
+
+		want := make(map[string]bool)
+		for _, dir := range c.Connections {
+			if dest, ok := c.Destinations[dir]; ok {
+				want["Signpost to "+dest.Name] = true
+			} else {
+				want["Signpost"] = true
+			}
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("chunk %d,%d: got signposts %v, want %v", c.ChunkX, c.ChunkY, names, want)
+		}
+	}
+}
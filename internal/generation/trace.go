@@ -0,0 +1,162 @@
+package generation
+
+import "strings"
+
+// Trace records what the generator did, for inspecting a chunk that came
+// out wrong or failed to generate. It is only collected when enabled with
+// ChunkGenerator.EnableTrace.
+type Trace struct {
+	Seed   uint64       `json:"seed"`
+	Phases []PhaseTrace `json:"phases"`
+	Nodes  []NodeTrace  `json:"nodes"`
+	Edges  []EdgeTrace  `json:"edges"`
+	Avoid  [][2]int     `json:"avoid"`           // Tiles paths were routed around
+	Error  string       `json:"error,omitempty"` // Why generation failed, if it did
+
+	draws []uint64 // RNG output since the last phase
+}
+
+// PhaseTrace is the state of the grid after one generation phase
+type PhaseTrace struct {
+	Phase    int      `json:"phase"`
+	Name     string   `json:"name"`
+	Tiles    []string `json:"tiles"`     // One string per row
+	RNGDraws []uint64 `json:"rng_draws"` // Raw RNG output during the phase
+}
+
+// NodeTrace is a graph node as it stood when generation finished
+type NodeTrace struct {
+	ID       string        `json:"id"`
+	Type     string        `json:"type"`
+	Position [2]int        `json:"position"`
+	Bounds   BoundsDef     `json:"bounds"`
+	Anchors  []AnchorTrace `json:"anchors"`
+}
+
+// AnchorTrace is a connection point on a node
+type AnchorTrace struct {
+	Position  [2]int `json:"position"`
+	Direction string `json:"direction"`
+}
+
+// EdgeTrace is a graph edge with the anchors it was routed between and the
+// path laid for it. Path is empty if the edge was never routed.
+type EdgeTrace struct {
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Weight     float64  `json:"weight"`
+	Required   bool     `json:"required"`
+	FromAnchor *[2]int  `json:"from_anchor,omitempty"`
+	ToAnchor   *[2]int  `json:"to_anchor,omitempty"`
+	Path       [][2]int `json:"path"`
+}
+
+// EnableTrace turns on trace recording for the next call to Generate
+func (cg *ChunkGenerator) EnableTrace() {
+	cg.trace = &Trace{Seed: cg.config.Seed, Avoid: make([][2]int, 0), draws: make([]uint64, 0)}
+	cg.rng.onDraw = func(v uint64) {
+		cg.trace.draws = append(cg.trace.draws, v)
+	}
+}
+
+// Trace returns the recorded trace, or nil if tracing wasn't enabled
+func (cg *ChunkGenerator) Trace() *Trace {
+	return cg.trace
+}
+
+// tracePhase snapshots the grid at the end of a phase
+func (cg *ChunkGenerator) tracePhase(name string) {
+	t := cg.trace
+	if t == nil {
+		return
+	}
+
+	tiles := make([]string, 0)
+	if cg.grid != nil {
+		for _, row := range cg.grid.Tiles {
+			tiles = append(tiles, strings.Join(row, ""))
+		}
+	}
+
+	t.Phases = append(t.Phases, PhaseTrace{
+		Phase:    len(t.Phases) + 1,
+		Name:     name,
+		Tiles:    tiles,
+		RNGDraws: t.draws,
+	})
+	t.draws = make([]uint64, 0)
+}
+
+// traceAvoid records the tiles paths are routed around
+func (cg *ChunkGenerator) traceAvoid(avoid map[Point]bool) {
+	if cg.trace == nil {
+		return
+	}
+
+	// Grid order keeps the output stable
+	cg.trace.Avoid = make([][2]int, 0, len(avoid))
+	for y := 0; y < cg.size; y++ {
+		for x := 0; x < cg.size; x++ {
+			if avoid[Point{x, y}] {
+				cg.trace.Avoid = append(cg.trace.Avoid, [2]int{x, y})
+			}
+		}
+	}
+}
+
+// traceGraph records the graph's nodes and edges as they stand
+func (cg *ChunkGenerator) traceGraph() {
+	t := cg.trace
+	if t == nil || cg.graph == nil {
+		return
+	}
+
+	t.Nodes = make([]NodeTrace, 0, len(cg.graph.Nodes))
+	for _, id := range cg.graph.NodeIDs() {
+		n := cg.graph.Nodes[id]
+		anchors := make([]AnchorTrace, len(n.Anchors))
+		for i, a := range n.Anchors {
+			anchors[i] = AnchorTrace{
+				Position:  [2]int{a.Position.X, a.Position.Y},
+				Direction: a.Direction.String(),
+			}
+		}
+		t.Nodes = append(t.Nodes, NodeTrace{
+			ID:       n.ID,
+			Type:     n.Type.String(),
+			Position: [2]int{n.Position.X, n.Position.Y},
+			Bounds:   BoundsDef{MinX: n.Bounds.MinX, MaxX: n.Bounds.MaxX, MinY: n.Bounds.MinY, MaxY: n.Bounds.MaxY},
+			Anchors:  anchors,
+		})
+	}
+
+	t.Edges = make([]EdgeTrace, 0, len(cg.graph.Edges))
+	for _, e := range cg.graph.Edges {
+		et := EdgeTrace{
+			From:     e.From,
+			To:       e.To,
+			Weight:   e.Weight,
+			Required: e.Required,
+			Path:     make([][2]int, len(e.Path)),
+		}
+		if e.Routed {
+			et.FromAnchor = &[2]int{e.FromAnchor.X, e.FromAnchor.Y}
+			et.ToAnchor = &[2]int{e.ToAnchor.X, e.ToAnchor.Y}
+		}
+		for i, p := range e.Path {
+			et.Path[i] = [2]int{p.X, p.Y}
+		}
+		t.Edges = append(t.Edges, et)
+	}
+}
+
+// traceFailure records why generation stopped, along with the graph and
+// grid as they were at that point
+func (cg *ChunkGenerator) traceFailure(phase string, err error) error {
+	if cg.trace != nil {
+		cg.tracePhase(phase + " (failed)")
+		cg.traceGraph()
+		cg.trace.Error = err.Error()
+	}
+	return err
+}
@@ -35,6 +35,21 @@ func (d Direction) Opposite() Direction {
 	return (d + 2) % 4
 }
 
+// String returns the lowercase name of the direction
+func (d Direction) String() string {
+	switch d {
+	case North:
+		return "north"
+	case East:
+		return "east"
+	case South:
+		return "south"
+	case West:
+		return "west"
+	}
+	return "unknown"
+}
+
 // Delta returns the x,y offset for moving in this direction
 func (d Direction) Delta() (int, int) {
 	switch d {
@@ -86,11 +101,27 @@ func (b Bounds) Center() Point {
 	return Point{(b.MinX + b.MaxX) / 2, (b.MinY + b.MaxY) / 2}
 }
 
+// SideCenter returns the middle tile of the given side of the bounds
+func (b Bounds) SideCenter(d Direction) Point {
+	center := b.Center()
+	switch d {
+	case North:
+		return Point{center.X, b.MinY}
+	case East:
+		return Point{b.MaxX, center.Y}
+	case West:
+		return Point{b.MinX, center.Y}
+	}
+	return Point{center.X, b.MaxY}
+}
+
 // Grid represents a 2D tile grid that components render onto
 type Grid struct {
 	Width, Height int
 	Tiles         [][]string
 	Walkable      [][]bool // Cached walkability for pathfinding
+
+	layers *gridLayers // Ground and ownership, only tracked when asked for
 }
 
 // NewGrid creates a new grid filled with a default tile
@@ -118,6 +149,9 @@ func (g *Grid) Set(p Point, tile string, walkable bool) {
 	if g.InBounds(p) {
 		g.Tiles[p.Y][p.X] = tile
 		g.Walkable[p.Y][p.X] = walkable
+		if g.layers != nil {
+			g.layers.record(p, tile)
+		}
 	}
 }
 
@@ -139,14 +173,36 @@ func (g *Grid) IsWalkable(p Point) bool {
 
 // Anchor represents a connection point on a component
 type Anchor struct {
-	Position Point
+	Position  Point
 	Direction Direction // Which direction the anchor faces (for path connections)
 }
 
-// Zone represents an interactive area tied to a project
+// Zone types
+const (
+	ZoneTypeProject    = "project"    // A project's structure or one of its exhibits
+	ZoneTypeSignpost   = "signpost"   // A sign at a chunk exit
+	ZoneTypeLore       = "lore"       // Flavour text about the world
+	ZoneTypeLandmark   = "landmark"   // A named place worth finding
+	ZoneTypeTeleporter = "teleporter" // Moves the player to Metadata["to"] ("x,y" world tiles)
+	ZoneTypeInterior   = "interior"   // Stepping in enters the linked interior
+	ZoneTypeExit       = "exit"       // Stepping in leaves an interior for the overworld
+)
+
+// Zone priorities, for where zones overlap (higher wins)
+const (
+	zonePriorityDoor     = 10 // Doors sit on their structure's walls
+	zonePrioritySignpost = 5
+)
+
+// Zone represents an interactive area on the map
 type Zone struct {
 	Name        string
 	Description string
 	Bounds      Bounds
 	ProjectID   string
+	Type        string
+	Interior    string            // Interior ID for ZoneTypeInterior zones
+	Mask        []string          // Rows over Bounds marking the zone's tiles with '#'; nil for the whole rectangle
+	Priority    int               // Higher wins where zones overlap
+	Metadata    map[string]string // Anything else the client may use
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -23,7 +24,8 @@ func NewGameHandler(gs *services.GameService, ms *services.MapService) *GameHand
 	}
 }
 
-// InitGame handles GET /api/game/init
+// InitGame handles GET /api/game/init - resumes the session's game, or
+// starts one at the spawn point
 func (h *GameHandler) InitGame(w http.ResponseWriter, r *http.Request) {
 	// Parse viewport dimensions from query params
 	width := parseIntParam(r, "width", 40)
@@ -33,19 +35,30 @@ func (h *GameHandler) InitGame(w http.ResponseWriter, r *http.Request) {
 	width = clamp(width, 10, 200)
 	height = clamp(height, 10, 100)
 
-	state := h.gameService.NewGame()
+	id, state, err := h.gameService.NewGame(sessionID(r))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to start game")
+		return
+	}
+	setSessionCookie(w, id)
 	viewport := h.mapService.GetViewport(state.PlayerPosition, width, height)
 
 	respondJSON(w, http.StatusOK, viewport)
 }
 
-// Move handles POST /api/game/move
+// Move handles POST /api/game/move - moves the player one step in
+// direction, or towards target along a route the server finds. The server
+// moves the player from where it last put them, starting a game at the spawn
+// point if the caller has none; position, if sent, must agree. A move that
+// disagrees (409) or comes faster than the move delay (429) returns the
+// viewport at the player's real position.
 func (h *GameHandler) Move(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Direction string          `json:"direction"`
-		Position  models.Position `json:"position"`
-		Width     int             `json:"width"`
-		Height    int             `json:"height"`
+		Direction string           `json:"direction"`
+		Target    *models.Position `json:"target"`
+		Position  *models.Position `json:"position"`
+		Width     int              `json:"width"`
+		Height    int              `json:"height"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -65,8 +78,28 @@ func (h *GameHandler) Move(w http.ResponseWriter, r *http.Request) {
 	req.Width = clamp(req.Width, 10, 200)
 	req.Height = clamp(req.Height, 10, 100)
 
-	newPos, err := h.gameService.Move(req.Position, req.Direction)
+	id, _, err := h.gameService.NewGame(sessionID(r))
 	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to start game")
+		return
+	}
+	setSessionCookie(w, id)
+
+	var newPos models.Position
+	if req.Target != nil {
+		newPos, err = h.gameService.MoveTowards(id, req.Position, *req.Target)
+	} else {
+		newPos, err = h.gameService.Move(id, req.Position, req.Direction)
+	}
+
+	switch {
+	case errors.Is(err, services.ErrPositionMismatch):
+		respondJSON(w, http.StatusConflict, h.mapService.GetViewport(newPos, req.Width, req.Height))
+		return
+	case errors.Is(err, services.ErrMoveTooSoon):
+		respondJSON(w, http.StatusTooManyRequests, h.mapService.GetViewport(newPos, req.Width, req.Height))
+		return
+	case err != nil:
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -5,11 +5,13 @@ import (
 	"log"
 	"net/http"
 	"path/filepath"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"dconn.dev/internal/config"
 	"dconn.dev/internal/middleware"
+	"dconn.dev/internal/models"
 	"dconn.dev/internal/services"
 )
 
@@ -23,21 +25,30 @@ func SetupRoutes(cfg *config.Config) http.Handler {
 
 	// Initialize services
 	mapService := services.NewMapService(cfg.GameMap)
-	gameService := services.NewGameService(mapService)
 	projectService := services.NewProjectService(cfg.Projects)
 
 	// Initialize world service for chunk-based maps
 	worldService, err := services.NewWorldService(cfg.DataPath)
 	if err != nil {
 		log.Printf("Warning: Failed to initialize WorldService: %v", err)
+	} else {
+		checkConsistency(cfg, worldService)
 	}
 
+	// Legacy games walk the legacy map, kept apart from world sessions so a
+	// move is always checked against the map its viewport is drawn from
+	moveDelay := time.Duration(cfg.GameConfig.MoveDelayMs) * time.Millisecond
+	gameSessions := services.NewSessionService(sessionStore(cfg, "legacy"), nil, mapService, moveDelay)
+	gameService := services.NewGameService(mapService, gameSessions)
+
 	// Initialize handlers
 	gameHandler := NewGameHandler(gameService, mapService)
-	projectHandler := NewProjectHandler(projectService)
+	projectHandler := NewProjectHandler(projectService, worldService)
 	var worldHandler *WorldHandler
+	var sessionHandler *SessionHandler
 	if worldService != nil {
 		worldHandler = NewWorldHandler(worldService)
+		sessionHandler = NewSessionHandler(services.NewSessionService(sessionStore(cfg, ""), worldService, mapService, moveDelay))
 	}
 
 	// API routes
@@ -50,12 +61,24 @@ func SetupRoutes(cfg *config.Config) http.Handler {
 		// World/chunk endpoints (new)
 		if worldHandler != nil {
 			r.Get("/world", worldHandler.GetWorld)
+			r.Get("/world/zones", worldHandler.QueryZones)
 			r.Get("/chunks/{x}/{y}", worldHandler.GetChunk)
+			r.Get("/chunks/{x}/{y}/interiors/{id}", worldHandler.GetInterior)
+
+			// Session endpoints
+			r.Post("/session", sessionHandler.StartSession)
+			r.Get("/session", sessionHandler.GetSession)
+			r.Delete("/session", sessionHandler.EndSession)
+			r.Put("/session/position", sessionHandler.UpdatePosition)
+			r.Put("/session/settings", sessionHandler.UpdateSettings)
+			r.Get("/session/fog", sessionHandler.GetFog)
 		}
 
 		// Project endpoints
 		r.Get("/projects", projectHandler.ListProjects)
 		r.Get("/projects/{id}", projectHandler.GetProject)
+		r.Get("/projects/{id}/path", projectHandler.GetPath)
+		r.Get("/tech", projectHandler.ListTech)
 
 		// Health check
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -67,6 +90,10 @@ func SetupRoutes(cfg *config.Config) http.Handler {
 	fileServer := http.FileServer(http.Dir("./static"))
 	r.Handle("/static/*", http.StripPrefix("/static", fileServer))
 
+	// Project media, one directory per project
+	mediaServer := http.FileServer(http.Dir(filepath.Join(cfg.DataPath, "media")))
+	r.Handle(services.MediaURLPrefix+"/*", http.StripPrefix(services.MediaURLPrefix, mediaServer))
+
 	// Serve index.html at root
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, filepath.Join("static", "index.html"))
@@ -75,6 +102,37 @@ func SetupRoutes(cfg *config.Config) http.Handler {
 	return r
 }
 
+// sessionStore returns where sessions are kept: files in subdir of
+// cfg.SessionDir if it's set, otherwise memory
+func sessionStore(cfg *config.Config, subdir string) services.SessionStore {
+	if cfg.SessionDir == "" {
+		return services.NewMemoryStore()
+	}
+	store, err := services.NewFileStore(filepath.Join(cfg.SessionDir, subdir))
+	if err != nil {
+		log.Printf("Warning: %v; keeping sessions in memory", err)
+		return services.NewMemoryStore()
+	}
+	return store
+}
+
+// checkConsistency makes sure every project placed in the world is in the
+// catalogue and every featured project is placed once, warning about any
+// that aren't or, in strict mode, refusing to start
+func checkConsistency(cfg *config.Config, ws *services.WorldService) {
+	report := models.CheckConsistency(cfg.Projects.Projects, ws.Placements())
+	if report.OK() {
+		return
+	}
+
+	for _, problem := range report.Problems() {
+		log.Printf("Warning: %s", problem)
+	}
+	if cfg.StrictConsistency {
+		log.Fatalf("World and projects.json disagree (%d problems)", len(report.Problems()))
+	}
+}
+
 // respondJSON writes a JSON response
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
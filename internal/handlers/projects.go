@@ -2,37 +2,124 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 
+	"dconn.dev/internal/models"
 	"dconn.dev/internal/services"
 )
 
 // ProjectHandler handles project-related endpoints
 type ProjectHandler struct {
 	projectService *services.ProjectService
+	worldService   *services.WorldService // nil when there is no chunk world
 }
 
-// NewProjectHandler creates a new ProjectHandler
-func NewProjectHandler(ps *services.ProjectService) *ProjectHandler {
-	return &ProjectHandler{projectService: ps}
+// NewProjectHandler creates a new ProjectHandler. ws may be nil, in which
+// case projects have no locations.
+func NewProjectHandler(ps *services.ProjectService, ws *services.WorldService) *ProjectHandler {
+	return &ProjectHandler{projectService: ps, worldService: ws}
 }
 
-// ListProjects handles GET /api/projects
+// ListProjects handles GET /api/projects - lists projects, optionally
+// searched (q), filtered (tech, year, featured), sorted (sort) and paged
+// (page, per_page). The total number of matches is in X-Total-Count.
 func (h *ProjectHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
-	projects := h.projectService.GetAll()
-	respondJSON(w, http.StatusOK, projects)
+	query := r.URL.Query()
+	q := services.ProjectQuery{
+		Search: query.Get("q"),
+		Sort:   query.Get("sort"),
+	}
+	for _, tech := range query["tech"] {
+		q.Tech = append(q.Tech, strings.Split(tech, ",")...)
+	}
+
+	var err error
+	for _, param := range []struct {
+		name string
+		dest *int
+	}{{"year", &q.Year}, {"page", &q.Page}, {"per_page", &q.PerPage}} {
+		if v := query.Get(param.name); v != "" {
+			if *param.dest, err = strconv.Atoi(v); err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid "+param.name)
+				return
+			}
+		}
+	}
+	if v := query.Get("featured"); v != "" {
+		featured, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid featured")
+			return
+		}
+		q.Featured = &featured
+	}
+
+	page, err := h.projectService.Query(q)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	respondJSON(w, http.StatusOK, page.Projects)
+}
+
+// ListTech handles GET /api/tech - lists technologies and how many projects
+// use each
+func (h *ProjectHandler) ListTech(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.projectService.GetTech())
 }
 
-// GetProject handles GET /api/projects/{id}
+// GetProject handles GET /api/projects/{id} - returns a project, its page
+// and where it stands in the world
 func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	project, err := h.projectService.GetByID(id)
+	resp, err := h.projectService.GetPage(id)
 	if err != nil {
 		respondError(w, http.StatusNotFound, "Project not found")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, project)
+	if h.worldService != nil {
+		resp.Location = h.worldService.ProjectLocation(id)
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// GetPath handles GET /api/projects/{id}/path?from=x,y - returns a walkable
+// route from a world tile to the project
+func (h *ProjectHandler) GetPath(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if _, err := h.projectService.GetByID(id); err != nil {
+		respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if h.worldService == nil {
+		respondError(w, http.StatusNotFound, "No world to route through")
+		return
+	}
+
+	from, ok := parseInts(r.URL.Query().Get("from"), 2)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "Invalid from, expected x,y")
+		return
+	}
+
+	steps, err := h.worldService.PathToProject(models.Position{X: from[0], Y: from[1]}, id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.PathResponse{
+		ProjectID: id,
+		Steps:     steps,
+		Length:    len(steps) - 1,
+	})
 }
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"dconn.dev/internal/models"
+	"dconn.dev/internal/services"
+)
+
+// sessionCookie holds the session ID for browsers
+const sessionCookie = "session"
+
+// sessionHeader holds the session ID for clients that don't keep cookies
+const sessionHeader = "X-Session-ID"
+
+// sessionMaxAge is how long a browser keeps the session cookie, in seconds:
+// as long as the server keeps an idle session
+const sessionMaxAge = int(services.SessionTTL / time.Second)
+
+// SessionHandler handles session endpoints
+type SessionHandler struct {
+	sessionService *services.SessionService
+}
+
+// NewSessionHandler creates a new SessionHandler
+func NewSessionHandler(ss *services.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: ss}
+}
+
+// sessionID returns the session ID a request carries, from its header or
+// its cookie, or "" if it has none
+func sessionID(r *http.Request) string {
+	if id := r.Header.Get(sessionHeader); id != "" {
+		return id
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// setSessionCookie has the browser send a session ID with later requests
+func setSessionCookie(w http.ResponseWriter, id string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   sessionMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// StartSession handles POST /api/session - resumes the caller's session or
+// starts a new one at the spawn point
+func (h *SessionHandler) StartSession(w http.ResponseWriter, r *http.Request) {
+	session, resumed, err := h.sessionService.Start(sessionID(r))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to start session")
+		return
+	}
+
+	setSessionCookie(w, session.ID)
+	respondJSON(w, http.StatusOK, sessionResponse(session, resumed))
+}
+
+// GetSession handles GET /api/session - returns the caller's session
+func (h *SessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	session, err := h.sessionService.Get(sessionID(r))
+	if err != nil {
+		respondSessionError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, sessionResponse(session, true))
+}
+
+// UpdatePosition handles PUT /api/session/position - records where the
+// player stands. A position that couldn't have been walked to is refused
+// with 409 and the session, so the client can put the player back; one
+// reached faster than the move delay allows is refused with 429.
+func (h *SessionHandler) UpdatePosition(w http.ResponseWriter, r *http.Request) {
+	var pos models.Position
+	if err := json.NewDecoder(r.Body).Decode(&pos); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	session, err := h.sessionService.UpdatePosition(sessionID(r), pos)
+	if errors.Is(err, services.ErrRejectedPosition) {
+		respondJSON(w, http.StatusConflict, sessionResponse(session, true))
+		return
+	}
+	if errors.Is(err, services.ErrMoveTooSoon) {
+		respondJSON(w, http.StatusTooManyRequests, sessionResponse(session, true))
+		return
+	}
+	if err != nil {
+		respondSessionError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, sessionResponse(session, true))
+}
+
+// UpdateSettings handles PUT /api/session/settings - merges settings into
+// the session's, an empty value removing one
+func (h *SessionHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var settings map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	session, err := h.sessionService.UpdateSettings(sessionID(r), settings)
+	if err != nil {
+		respondSessionError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, sessionResponse(session, true))
+}
+
+// EndSession handles DELETE /api/session - forgets the caller's session
+func (h *SessionHandler) EndSession(w http.ResponseWriter, r *http.Request) {
+	if err := h.sessionService.End(sessionID(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to end session")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFog handles GET /api/session/fog - returns the explored mask of every
+// chunk the caller has seen part of
+func (h *SessionHandler) GetFog(w http.ResponseWriter, r *http.Request) {
+	fog, err := h.sessionService.Fog(sessionID(r))
+	if err != nil {
+		respondSessionError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, fog)
+}
+
+// sessionResponse prepares a session to send. Fog masks are left out, being
+// large and served by GetFog.
+func sessionResponse(session *models.Session, resumed bool) models.SessionResponse {
+	resp := models.SessionResponse{Session: *session, Resumed: resumed}
+	resp.Fog = nil
+	return resp
+}
+
+// respondSessionError reports a failed session lookup or update
+func respondSessionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrSessionNotFound) {
+		respondError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+	respondError(w, http.StatusBadRequest, err.Error())
+}
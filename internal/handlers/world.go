@@ -3,9 +3,11 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 
+	"dconn.dev/internal/models"
 	"dconn.dev/internal/services"
 )
 
@@ -50,3 +52,130 @@ func (h *WorldHandler) GetChunk(w http.ResponseWriter, r *http.Request) {
 
 	respondJSON(w, http.StatusOK, chunk)
 }
+
+// GetInterior handles GET /api/chunks/{x}/{y}/interiors/{id} - returns the
+// inside of a structure in a chunk
+func (h *WorldHandler) GetInterior(w http.ResponseWriter, r *http.Request) {
+	xStr := chi.URLParam(r, "x")
+	yStr := chi.URLParam(r, "y")
+	id := chi.URLParam(r, "id")
+
+	x, err := strconv.Atoi(xStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid x coordinate")
+		return
+	}
+
+	y, err := strconv.Atoi(yStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid y coordinate")
+		return
+	}
+
+	interior, err := h.worldService.GetInterior(x, y, id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, interior)
+}
+
+// QueryZones handles GET /api/world/zones - finds zones by world tile.
+// Exactly one of at=x,y (zones containing a tile), in=minX,minY,maxX,maxY
+// (zones overlapping a rectangle) or near=x,y (the nearest zone) is given,
+// optionally narrowed by type and project.
+func (h *WorldHandler) QueryZones(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	kind := query.Get("type")
+	project := query.Get("project")
+	keep := func(z *models.WorldZone) bool {
+		return (kind == "" || z.Kind() == kind) && (project == "" || z.ProjectID == project)
+	}
+
+	var resp models.ZoneQueryResponse
+	switch {
+	case query.Has("at"):
+		at, ok := parseCoords(query.Get("at"), 2)
+		if !ok {
+			respondError(w, http.StatusBadRequest, "Invalid at, expected x,y")
+			return
+		}
+		resp.Zones = filterZones(h.worldService.ZonesAt(at[0], at[1]), keep)
+
+	case query.Has("in"):
+		in, ok := parseCoords(query.Get("in"), 4)
+		if !ok || in[0] > in[2] || in[1] > in[3] {
+			respondError(w, http.StatusBadRequest, "Invalid in, expected minX,minY,maxX,maxY")
+			return
+		}
+		bounds := models.Bounds{MinX: in[0], MinY: in[1], MaxX: in[2], MaxY: in[3]}
+		resp.Zones = filterZones(h.worldService.ZonesIn(bounds), keep)
+
+	case query.Has("near"):
+		near, ok := parseCoords(query.Get("near"), 2)
+		if !ok {
+			respondError(w, http.StatusBadRequest, "Invalid near, expected x,y")
+			return
+		}
+		resp.Zones = []models.WorldZone{}
+		if zone, distance, found := h.worldService.NearestZone(near[0], near[1], keep); found {
+			resp.Zones = append(resp.Zones, zone)
+			resp.Distance = &distance
+		}
+
+	default:
+		respondError(w, http.StatusBadRequest, "Expected one of at, in or near")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// filterZones returns the zones keep accepts, never nil so the response
+// always has a list
+func filterZones(zones []models.WorldZone, keep func(*models.WorldZone) bool) []models.WorldZone {
+	out := make([]models.WorldZone, 0, len(zones))
+	for i := range zones {
+		if keep(&zones[i]) {
+			out = append(out, zones[i])
+		}
+	}
+	return out
+}
+
+// maxZoneCoord bounds the world coordinates a zone query accepts, far beyond
+// any world the generator makes
+const maxZoneCoord = 1 << 20
+
+// parseCoords parses a comma-separated list of exactly n world coordinates,
+// rejecting any beyond maxZoneCoord
+func parseCoords(s string, n int) ([]int, bool) {
+	coords, ok := parseInts(s, n)
+	if !ok {
+		return nil, false
+	}
+	for _, c := range coords {
+		if c < -maxZoneCoord || c > maxZoneCoord {
+			return nil, false
+		}
+	}
+	return coords, true
+}
+
+// parseInts parses a comma-separated list of exactly n integers
+func parseInts(s string, n int) ([]int, bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, false
+	}
+	out := make([]int, n)
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, false
+		}
+		out[i] = v
+	}
+	return out, true
+}
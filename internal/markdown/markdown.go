@@ -0,0 +1,488 @@
+// Package markdown renders the small subset of markdown project pages use:
+// headings, paragraphs, lists, quotes, rules, fenced code, emphasis, inline
+// code, links and images. Raw HTML is never passed through, so the output is
+// safe to insert into a page.
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Render converts markdown to sanitized HTML and to plain text for the ASCII
+// UI. Relative link and image URLs are resolved against base, if it isn't
+// empty.
+func Render(src, base string) (htmlOut, textOut string) {
+	blocks := parseBlocks(src)
+
+	var h, t []string
+	for _, b := range blocks {
+		h = append(h, b.html(base))
+		t = append(t, b.text(base))
+	}
+	return strings.Join(h, "\n"), strings.Join(t, "\n\n")
+}
+
+type blockKind int
+
+const (
+	paragraph blockKind = iota
+	heading
+	code
+	list
+	quote
+	rule
+)
+
+// block is a top-level piece of a document
+type block struct {
+	kind    blockKind
+	level   int      // Heading level
+	ordered bool     // Numbered list
+	lines   []string // Paragraph and quote lines, code lines, or list items
+}
+
+// parseBlocks splits a document into blocks
+func parseBlocks(src string) []block {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	var blocks []block
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			b := block{kind: code}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				b.lines = append(b.lines, lines[i])
+			}
+			blocks = append(blocks, b)
+			i++ // The closing fence
+
+		case headingLevel(trimmed) > 0:
+			level := headingLevel(trimmed)
+			title := strings.TrimSpace(strings.TrimRight(trimmed[level:], "#"))
+			blocks = append(blocks, block{kind: heading, level: level, lines: []string{title}})
+			i++
+
+		case isRule(trimmed):
+			blocks = append(blocks, block{kind: rule})
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			b := block{kind: quote}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				b.lines = append(b.lines, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			blocks = append(blocks, b)
+
+		case listItem(trimmed) != "":
+			_, ordered := orderedItem(trimmed)
+			b := block{kind: list, ordered: ordered}
+			for ; i < len(lines); i++ {
+				line := strings.TrimSpace(lines[i])
+				if item := listItem(line); item != "" {
+					if _, numbered := orderedItem(line); numbered != ordered {
+						break // A different kind of list
+					}
+					b.lines = append(b.lines, item)
+				} else if line != "" && !startsBlock(line) && len(b.lines) > 0 {
+					b.lines[len(b.lines)-1] += " " + line // A wrapped item
+				} else {
+					break
+				}
+			}
+			blocks = append(blocks, b)
+
+		default:
+			b := block{kind: paragraph}
+			for ; i < len(lines); i++ {
+				line := strings.TrimSpace(lines[i])
+				if line == "" || (len(b.lines) > 0 && startsBlock(line)) {
+					break
+				}
+				b.lines = append(b.lines, line)
+			}
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// startsBlock reports whether a line begins a block other than a paragraph
+func startsBlock(line string) bool {
+	return strings.HasPrefix(line, "```") || headingLevel(line) > 0 || isRule(line) ||
+		strings.HasPrefix(line, ">") || listItem(line) != ""
+}
+
+// headingLevel returns how many #s open a heading line, or 0 if it isn't one
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0
+	}
+	return level
+}
+
+// isRule reports whether a line is a horizontal rule: three or more of the
+// same -, * or _, spaces allowed between
+func isRule(line string) bool {
+	stripped := strings.ReplaceAll(line, " ", "")
+	if len(stripped) < 3 || !strings.ContainsRune("-*_", rune(stripped[0])) {
+		return false
+	}
+	return strings.Count(stripped, stripped[:1]) == len(stripped)
+}
+
+// listItem returns the text of a list item line, or "" if it isn't one
+func listItem(line string) string {
+	for _, marker := range []string{"- ", "* ", "+ "} {
+		if strings.HasPrefix(line, marker) {
+			return strings.TrimSpace(line[len(marker):])
+		}
+	}
+	item, _ := orderedItem(line)
+	return item
+}
+
+// orderedItem returns the text of a numbered list item line ("1. text")
+func orderedItem(line string) (string, bool) {
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || !strings.HasPrefix(line[digits:], ". ") {
+		return "", false
+	}
+	return strings.TrimSpace(line[digits+2:]), true
+}
+
+// html renders a block
+func (b block) html(base string) string {
+	switch b.kind {
+	case heading:
+		return fmt.Sprintf("<h%d>%s</h%d>", b.level, inlineHTML(parseInline(b.lines[0]), base), b.level)
+	case code:
+		return "<pre><code>" + html.EscapeString(strings.Join(b.lines, "\n")) + "</code></pre>"
+	case list:
+		tag := "ul"
+		if b.ordered {
+			tag = "ol"
+		}
+		var s strings.Builder
+		s.WriteString("<" + tag + ">")
+		for _, item := range b.lines {
+			s.WriteString("<li>" + inlineHTML(parseInline(item), base) + "</li>")
+		}
+		s.WriteString("</" + tag + ">")
+		return s.String()
+	case quote:
+		return "<blockquote><p>" + inlineHTML(parseInline(strings.Join(b.lines, " ")), base) + "</p></blockquote>"
+	case rule:
+		return "<hr>"
+	default:
+		return "<p>" + inlineHTML(parseInline(strings.Join(b.lines, " ")), base) + "</p>"
+	}
+}
+
+// text renders a block as plain text
+func (b block) text(base string) string {
+	switch b.kind {
+	case heading:
+		title := inlineText(parseInline(b.lines[0]), base)
+		if b.level > 2 {
+			return title
+		}
+		underline := "="
+		if b.level == 2 {
+			underline = "-"
+		}
+		return title + "\n" + strings.Repeat(underline, utf8.RuneCountInString(title))
+	case code:
+		lines := make([]string, len(b.lines))
+		for i, line := range b.lines {
+			lines[i] = "    " + line
+		}
+		return strings.Join(lines, "\n")
+	case list:
+		lines := make([]string, len(b.lines))
+		for i, item := range b.lines {
+			marker := "-"
+			if b.ordered {
+				marker = fmt.Sprintf("%d.", i+1)
+			}
+			lines[i] = marker + " " + inlineText(parseInline(item), base)
+		}
+		return strings.Join(lines, "\n")
+	case quote:
+		return "> " + inlineText(parseInline(strings.Join(b.lines, " ")), base)
+	case rule:
+		return "----"
+	default:
+		return inlineText(parseInline(strings.Join(b.lines, " ")), base)
+	}
+}
+
+type inlineKind int
+
+const (
+	text inlineKind = iota
+	codeSpan
+	strong
+	emphasis
+	link
+	image
+)
+
+// inline is a run of text within a block
+type inline struct {
+	kind     inlineKind
+	text     string // Text, code or image alt text
+	url      string // Link or image target
+	children []inline
+}
+
+// escapable are the characters a backslash makes literal
+const escapable = "\\`*_[]()#+-.!>"
+
+// parseInline splits a line into runs of text and formatting
+func parseInline(s string) []inline {
+	var out []inline
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			out = append(out, inline{kind: text, text: buf.String()})
+			buf.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) >= 0:
+			buf.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				flush()
+				out = append(out, inline{kind: codeSpan, text: s[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if label, target, n, ok := linkAt(s[i+1:]); ok {
+				flush()
+				out = append(out, inline{kind: image, text: label, url: target})
+				i += 1 + n
+				continue
+			}
+
+		case c == '[':
+			if label, target, n, ok := linkAt(s[i:]); ok {
+				flush()
+				out = append(out, inline{kind: link, url: target, children: parseInline(label)})
+				i += n
+				continue
+			}
+
+		case c == '*' || c == '_':
+			if c == '_' && i > 0 && isWordByte(s[i-1]) {
+				break // Inside a word, like snake_case
+			}
+			delim := s[i : i+1]
+			kind := emphasis
+			if strings.HasPrefix(s[i:], delim+delim) {
+				delim += delim
+				kind = strong
+			}
+			rest := s[i+len(delim):]
+			end := closingDelim(rest, delim)
+			if end > 0 && (c == '*' || end+len(delim) >= len(rest) || !isWordByte(rest[end+len(delim)])) {
+				flush()
+				out = append(out, inline{kind: kind, children: parseInline(rest[:end])})
+				i += len(delim)*2 + end
+				continue
+			}
+		}
+
+		buf.WriteByte(c)
+		i++
+	}
+	flush()
+	return out
+}
+
+// closingDelim returns where delim next closes in s, or -1. A single
+// delimiter passes over doubled ones, so emphasis can hold strong text.
+func closingDelim(s, delim string) int {
+	for i := 0; i < len(s); {
+		end := strings.Index(s[i:], delim)
+		if end < 0 {
+			return -1
+		}
+		end += i
+		if len(delim) == 1 && strings.HasPrefix(s[end:], delim+delim) {
+			i = end + 2
+			continue
+		}
+		return end
+	}
+	return -1
+}
+
+// linkAt parses "[label](target)" at the start of s, returning how many
+// bytes it takes up
+func linkAt(s string) (label, target string, n int, ok bool) {
+	closing := strings.IndexByte(s, ']')
+	if closing < 0 || !strings.HasPrefix(s[closing+1:], "(") {
+		return "", "", 0, false
+	}
+	// The target ends at the parenthesis that balances the opening one
+	end, depth := -1, 0
+	for i, c := range s[closing+2:] {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth == 0 {
+				end = i
+				break
+			}
+			depth--
+		}
+	}
+	if end < 0 {
+		return "", "", 0, false
+	}
+	target = strings.TrimSpace(s[closing+2 : closing+2+end])
+	if fields := strings.Fields(target); len(fields) > 0 {
+		target = fields[0] // Drop any title
+	}
+	return s[1:closing], target, closing + 3 + end, true
+}
+
+// isWordByte reports whether b is a letter or digit
+func isWordByte(b byte) bool {
+	r := rune(b)
+	return b < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// safeURL resolves a link or image target against base, returning "" if it
+// uses a scheme that isn't safe to put in a page
+func safeURL(target, base string) string {
+	u, err := url.Parse(target)
+	if err != nil || target == "" {
+		return ""
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return target
+	case "":
+		if base != "" && u.Host == "" && !strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "#") {
+			return strings.TrimRight(base, "/") + "/" + target
+		}
+		return target
+	default:
+		return ""
+	}
+}
+
+// inlineHTML renders runs of inline text as HTML
+func inlineHTML(runs []inline, base string) string {
+	var s strings.Builder
+	for _, r := range runs {
+		switch r.kind {
+		case codeSpan:
+			s.WriteString("<code>" + html.EscapeString(r.text) + "</code>")
+		case strong:
+			s.WriteString("<strong>" + inlineHTML(r.children, base) + "</strong>")
+		case emphasis:
+			s.WriteString("<em>" + inlineHTML(r.children, base) + "</em>")
+		case link:
+			if href := safeURL(r.url, base); href != "" {
+				s.WriteString(`<a href="` + html.EscapeString(href) + `" rel="noopener noreferrer">` + inlineHTML(r.children, base) + "</a>")
+			} else {
+				s.WriteString(inlineHTML(r.children, base))
+			}
+		case image:
+			if src := safeURL(r.url, base); src != "" {
+				s.WriteString(`<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(r.text) + `">`)
+			} else {
+				s.WriteString(html.EscapeString(r.text))
+			}
+		default:
+			s.WriteString(html.EscapeString(r.text))
+		}
+	}
+	return s.String()
+}
+
+// inlineText renders runs of inline text as plain text, keeping link
+// targets readable
+func inlineText(runs []inline, base string) string {
+	var s strings.Builder
+	for _, r := range runs {
+		switch r.kind {
+		case strong, emphasis:
+			s.WriteString(inlineText(r.children, base))
+		case link:
+			label := inlineText(r.children, base)
+			s.WriteString(label)
+			if href := safeURL(r.url, base); href != "" && href != label {
+				s.WriteString(" (" + href + ")")
+			}
+		case image:
+			s.WriteString("[image: " + r.text + "]")
+		default:
+			s.WriteString(r.text)
+		}
+	}
+	return s.String()
+}
+
+// Summary returns the plain text of a document's first paragraph, skipping
+// paragraphs that are only images such as badges, or "" if there is none
+func Summary(src string) string {
+	for _, b := range parseBlocks(src) {
+		if b.kind != paragraph {
+			continue
+		}
+		runs := parseInline(strings.Join(b.lines, " "))
+		if !decorative(runs) {
+			return b.text("")
+		}
+	}
+	return ""
+}
+
+// decorative reports whether runs of inline text are only images, linked
+// or not, and spaces
+func decorative(runs []inline) bool {
+	for _, r := range runs {
+		switch r.kind {
+		case image:
+		case link:
+			if !decorative(r.children) {
+				return false
+			}
+		case text:
+			if strings.TrimSpace(r.text) != "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package markdown
+
+import "testing"
+
+// Nothing in the source reaches the page as markup: raw HTML is escaped and
+// links that could run script are dropped, keeping their text
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		name, src, html string
+	}{
+		{"script", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"raw html", "a <b onclick=x>bold</b>", "<p>a &lt;b onclick=x&gt;bold&lt;/b&gt;</p>"},
+		{"raw image", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>"},
+		{"html in heading", "# Title <i>", "<h1>Title &lt;i&gt;</h1>"},
+		{"html in list", "- <u>x</u>", "<ul><li>&lt;u&gt;x&lt;/u&gt;</li></ul>"},
+		{"html in code", "`<b>`", "<p><code>&lt;b&gt;</code></p>"},
+		{"javascript link", "[click](javascript:alert(1))", "<p>click</p>"},
+		{"javascript link any case", "[click](JavaScript:alert(1))", "<p>click</p>"},
+		{"data image", "![pic](data:text/html,x)", "<p>pic</p>"},
+		{"quote in url", `[a](https://example.com/?q="x)`, `<p><a href="https://example.com/?q=&#34;x" rel="noopener noreferrer">a</a></p>`},
+		{"mailto link", "[mail](mailto:me@example.com)", `<p><a href="mailto:me@example.com" rel="noopener noreferrer">mail</a></p>`},
+		{"relative link", "[doc](notes.md)", `<p><a href="/media/p/notes.md" rel="noopener noreferrer">doc</a></p>`},
+	}
+	for _, tt := range tests {
+		if got, _ := Render(tt.src, "/media/p"); got != tt.html {
+			t.Errorf("%s: Render(%q) = %q, want %q", tt.name, tt.src, got, tt.html)
+		}
+	}
+}
+
+// Emphasis and strong text nest either way round
+func TestRenderNestedEmphasis(t *testing.T) {
+	tests := []struct {
+		src, html string
+	}{
+		{"*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>"},
+		{"**a *b* c**", "<p><strong>a <em>b</em> c</strong></p>"},
+		{"_a **b** c_", "<p><em>a <strong>b</strong> c</em></p>"},
+		{"**[a *b*](https://example.com)**", `<p><strong><a href="https://example.com" rel="noopener noreferrer">a <em>b</em></a></strong></p>`},
+		{"*unclosed **b**", "<p>*unclosed <strong>b</strong></p>"},
+	}
+	for _, tt := range tests {
+		if got, _ := Render(tt.src, ""); got != tt.html {
+			t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.html)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Placement is a project placed in the world
+type Placement struct {
+	ProjectID string
+	Name      string // The structure's in-world name
+	ChunkX    int
+	ChunkY    int
+}
+
+// ConsistencyReport lists where the world and the project catalogue disagree
+type ConsistencyReport struct {
+	Orphans    []Placement   // Placements of projects the catalogue doesn't have
+	Unplaced   []Project     // Featured projects placed nowhere
+	Duplicates [][]Placement // Projects placed more than once, one list each
+}
+
+// CheckConsistency compares the projects placed in the world with the
+// catalogue. Placements are reported in chunk order, projects in catalogue
+// order.
+func CheckConsistency(projects []Project, placements []Placement) *ConsistencyReport {
+	sorted := append([]Placement(nil), placements...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ChunkY != sorted[j].ChunkY {
+			return sorted[i].ChunkY < sorted[j].ChunkY
+		}
+		return sorted[i].ChunkX < sorted[j].ChunkX
+	})
+
+	known := make(map[string]bool, len(projects))
+	for _, p := range projects {
+		known[p.ID] = true
+	}
+
+	report := &ConsistencyReport{}
+	placed := make(map[string][]Placement)
+	var order []string
+	for _, pl := range sorted {
+		if !known[pl.ProjectID] {
+			report.Orphans = append(report.Orphans, pl)
+			continue
+		}
+		if placed[pl.ProjectID] == nil {
+			order = append(order, pl.ProjectID)
+		}
+		placed[pl.ProjectID] = append(placed[pl.ProjectID], pl)
+	}
+
+	for _, id := range order {
+		if len(placed[id]) > 1 {
+			report.Duplicates = append(report.Duplicates, placed[id])
+		}
+	}
+	for _, p := range projects {
+		if p.Featured && placed[p.ID] == nil {
+			report.Unplaced = append(report.Unplaced, p)
+		}
+	}
+
+	return report
+}
+
+// OK reports whether the world and catalogue agree
+func (r *ConsistencyReport) OK() bool {
+	return len(r.Orphans) == 0 && len(r.Unplaced) == 0 && len(r.Duplicates) == 0
+}
+
+// Problems describes each disagreement on a line of its own
+func (r *ConsistencyReport) Problems() []string {
+	var lines []string
+	for _, pl := range r.Orphans {
+		lines = append(lines, fmt.Sprintf("%q in chunk (%d, %d) is placed as project %q, which is not in projects.json",
+			pl.Name, pl.ChunkX, pl.ChunkY, pl.ProjectID))
+	}
+	for _, p := range r.Unplaced {
+		lines = append(lines, fmt.Sprintf("featured project %q is not placed in any chunk", p.ID))
+	}
+	for _, dups := range r.Duplicates {
+		line := fmt.Sprintf("project %q is placed %d times:", dups[0].ProjectID, len(dups))
+		for i, pl := range dups {
+			if i > 0 {
+				line += ","
+			}
+			line += fmt.Sprintf(" chunk (%d, %d)", pl.ChunkX, pl.ChunkY)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
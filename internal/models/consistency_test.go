@@ -0,0 +1,72 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Each fault the check looks for is found in a world built to have it, and
+// only there
+func TestCheckConsistency(t *testing.T) {
+	catalogue := []Project{
+		{ID: "tower", Featured: true},
+		{ID: "hut", Featured: true},
+		{ID: "shed"}, // Not featured, so it may go unplaced
+	}
+	tower := Placement{ProjectID: "tower", Name: "Tower", ChunkX: 0, ChunkY: 0}
+	hut := Placement{ProjectID: "hut", Name: "Hut", ChunkX: 1, ChunkY: 0}
+
+	tests := []struct {
+		name       string
+		placements []Placement
+		want       ConsistencyReport
+		problems   []string
+	}{
+		{
+			name:       "consistent",
+			placements: []Placement{hut, tower},
+		},
+		{
+			name:       "orphan",
+			placements: []Placement{tower, hut, {ProjectID: "ghost", Name: "Ruin", ChunkX: -1, ChunkY: 2}},
+			want:       ConsistencyReport{Orphans: []Placement{{ProjectID: "ghost", Name: "Ruin", ChunkX: -1, ChunkY: 2}}},
+			problems:   []string{`"Ruin" in chunk (-1, 2) is placed as project "ghost", which is not in projects.json`},
+		},
+		{
+			name:       "unplaced",
+			placements: []Placement{tower},
+			want:       ConsistencyReport{Unplaced: []Project{catalogue[1]}},
+			problems:   []string{`featured project "hut" is not placed in any chunk`},
+		},
+		{
+			name: "duplicate",
+			placements: []Placement{
+				{ProjectID: "tower", Name: "Tower", ChunkX: 0, ChunkY: 1},
+				hut, tower,
+			},
+			want:     ConsistencyReport{Duplicates: [][]Placement{{tower, {ProjectID: "tower", Name: "Tower", ChunkX: 0, ChunkY: 1}}}},
+			problems: []string{`project "tower" is placed 2 times: chunk (0, 0), chunk (0, 1)`},
+		},
+		{
+			name:       "nothing placed",
+			placements: nil,
+			want:       ConsistencyReport{Unplaced: []Project{catalogue[0], catalogue[1]}},
+			problems: []string{
+				`featured project "tower" is not placed in any chunk`,
+				`featured project "hut" is not placed in any chunk`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		report := CheckConsistency(catalogue, tt.placements)
+		if !reflect.DeepEqual(*report, tt.want) {
+			t.Errorf("%s: report %+v, want %+v", tt.name, *report, tt.want)
+		}
+		if report.OK() != (tt.problems == nil) {
+			t.Errorf("%s: OK() = %v with problems %v", tt.name, report.OK(), report.Problems())
+		}
+		if got := report.Problems(); !reflect.DeepEqual(got, tt.problems) {
+			t.Errorf("%s: problems %q, want %q", tt.name, got, tt.problems)
+		}
+	}
+}
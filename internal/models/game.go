@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Position represents a coordinate on the game map
 type Position struct {
 	X int `json:"x"`
@@ -19,6 +21,7 @@ type Tile struct {
 	Color     string `json:"color"`
 	Type      string `json:"type"` // water, grass, sand, building, etc.
 	Walkable  bool   `json:"walkable"`
+	Opaque    bool   `json:"opaque,omitempty"` // Blocks line of sight
 }
 
 // RenderedTile represents a tile as sent to the client
@@ -26,3 +29,29 @@ type RenderedTile struct {
 	Character string `json:"char"`
 	Color     string `json:"color"`
 }
+
+// Session is a visitor's saved progress, kept by the server between visits
+type Session struct {
+	ID             string              `json:"id"`
+	Position       Position            `json:"position"`            // Last confirmed overworld tile
+	LastMove       time.Time           `json:"last_move,omitempty"` // When Position was last confirmed
+	ExploredChunks []string            `json:"explored_chunks"`     // "x,y" of every chunk visited, in order
+	VisitedZones   []string            `json:"visited_zones"`       // Keys of every zone entered, in order
+	Settings       map[string]string   `json:"settings,omitempty"`  // Client preferences, stored as given
+	Fog            map[string][]string `json:"fog,omitempty"`       // Explored mask per chunk "x,y": a row per tile row, '#' where seen
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// SessionResponse is a session as sent to the client
+type SessionResponse struct {
+	Session
+	Resumed bool `json:"resumed"` // An existing session was found
+}
+
+// FogResponse is what a session has explored of the world, as masks per
+// chunk. Chunks it has seen nothing of are left out.
+type FogResponse struct {
+	ChunkSize int                 `json:"chunk_size"`
+	Chunks    map[string][]string `json:"chunks"` // "x,y" to a row per tile row, '#' where seen
+}
@@ -5,6 +5,7 @@ type World struct {
 	ChunkSize       int                 `json:"chunk_size"`
 	SpawnChunk      [2]int              `json:"spawn_chunk"`
 	SpawnLocal      [2]int              `json:"spawn_local"`
+	Palette         string              `json:"palette,omitempty"` // Theme the chunks were generated with
 	TileDefinitions map[string]Tile     `json:"tile_definitions"`
 	Chunks          map[string]ChunkRef `json:"chunks"`
 }
@@ -17,16 +18,60 @@ type ChunkRef struct {
 
 // Chunk represents a single map chunk
 type Chunk struct {
+	Tiles     [][]string  `json:"tiles"`
+	Zones     []Zone      `json:"zones"`
+	Interiors []Interior  `json:"interiors,omitempty"`
+	Layers    *TileLayers `json:"layers,omitempty"`
+}
+
+// TileLayers are optional per-tile data parallel to a tile grid. Each layer
+// that is present has the same dimensions as the tiles.
+type TileLayers struct {
+	Ground    [][]string `json:"ground,omitempty"`    // Terrain under whatever stands on it
+	Overlay   [][]string `json:"overlay,omitempty"`   // What stands on the ground, "" for nothing
+	Collision []string   `json:"collision,omitempty"` // One string per row: '#' blocks movement, '.' doesn't
+	Owner     [][]int    `json:"owner,omitempty"`     // Index into Owners, -1 for none
+	Owners    []string   `json:"owners,omitempty"`    // IDs of the components that drew tiles
+	Elevation [][]int    `json:"elevation,omitempty"` // Height relative to open ground
+}
+
+// Blocked reports whether the collision layer blocks movement at (x, y).
+// ok is false when there is no collision data for that tile, in which case
+// the tile definition decides.
+func (l *TileLayers) Blocked(x, y int) (blocked, ok bool) {
+	if l == nil || y < 0 || y >= len(l.Collision) || x < 0 || x >= len(l.Collision[y]) {
+		return false, false
+	}
+	return l.Collision[y][x] == '#', true
+}
+
+// Interior is the walkable inside of a structure in a chunk
+type Interior struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name"`
 	Tiles [][]string `json:"tiles"`
 	Zones []Zone     `json:"zones"`
+	Spawn [2]int     `json:"spawn"`
+}
+
+// InteriorResponse is what we send to the client for an interior
+type InteriorResponse struct {
+	ChunkX int        `json:"chunk_x"`
+	ChunkY int        `json:"chunk_y"`
+	ID     string     `json:"id"`
+	Name   string     `json:"name"`
+	Tiles  [][]string `json:"tiles"`
+	Zones  []Zone     `json:"zones"`
+	Spawn  [2]int     `json:"spawn"`
 }
 
 // ChunkResponse is what we send to the client
 type ChunkResponse struct {
-	X     int        `json:"x"`
-	Y     int        `json:"y"`
-	Tiles [][]string `json:"tiles"`
-	Zones []Zone     `json:"zones"`
+	X      int         `json:"x"`
+	Y      int         `json:"y"`
+	Tiles  [][]string  `json:"tiles"`
+	Zones  []Zone      `json:"zones"`
+	Layers *TileLayers `json:"layers,omitempty"`
 }
 
 // WorldResponse is the manifest sent to the client
@@ -40,20 +85,112 @@ type WorldResponse struct {
 
 // GameMap represents the entire game world (legacy, kept for compatibility)
 type GameMap struct {
-	Width           int                `json:"width"`
-	Height          int                `json:"height"`
-	Tiles           [][]string         `json:"tiles"`
-	TileDefinitions map[string]Tile    `json:"tile_definitions"`
-	Zones           []Zone             `json:"zones"`
-	Spawn           Position           `json:"spawn"`
+	Width           int             `json:"width"`
+	Height          int             `json:"height"`
+	Tiles           [][]string      `json:"tiles"`
+	TileDefinitions map[string]Tile `json:"tile_definitions"`
+	Zones           []Zone          `json:"zones"`
+	Spawn           Position        `json:"spawn"`
+	Layers          *TileLayers     `json:"layers,omitempty"`
 }
 
+// Zone types
+const (
+	ZoneProject    = "project"    // A project's structure or one of its exhibits
+	ZoneSignpost   = "signpost"   // A sign at a chunk exit
+	ZoneLore       = "lore"       // Flavour text about the world
+	ZoneLandmark   = "landmark"   // A named place worth finding
+	ZoneTeleporter = "teleporter" // Moves the player to metadata["to"] ("x,y" world tiles)
+	ZoneInterior   = "interior"   // A door into the linked interior
+	ZoneExit       = "exit"       // The way out of an interior
+)
+
 // Zone represents an interactive area on the map
 type Zone struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Bounds      Bounds `json:"bounds"`
-	ProjectID   string `json:"project_id,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Bounds      Bounds            `json:"bounds"`
+	ProjectID   string            `json:"project_id,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Interior    string            `json:"interior,omitempty"` // Interior ID a door leads to
+	Mask        []string          `json:"mask,omitempty"`     // Rows over Bounds; only '#' tiles are in the zone
+	Priority    int               `json:"priority,omitempty"` // Higher wins where zones overlap
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Kind returns the zone's type, working it out for zones generated before
+// zones were typed
+func (z *Zone) Kind() string {
+	switch {
+	case z.Type != "":
+		return z.Type
+	case z.Name == "Signpost":
+		return ZoneSignpost
+	case z.ProjectID != "":
+		return ZoneProject
+	default:
+		return ZoneLandmark
+	}
+}
+
+// Contains reports whether the tile (x, y) is in the zone
+func (z *Zone) Contains(x, y int) bool {
+	b := z.Bounds
+	if x < b.MinX || x > b.MaxX || y < b.MinY || y > b.MaxY {
+		return false
+	}
+	if z.Mask == nil {
+		return true
+	}
+	row := y - b.MinY
+	col := x - b.MinX
+	return row < len(z.Mask) && col < len(z.Mask[row]) && z.Mask[row][col] == '#'
+}
+
+// area is the number of tiles in the zone's bounds, used to prefer the more
+// specific of two overlapping zones
+func (z *Zone) area() int {
+	return (z.Bounds.MaxX - z.Bounds.MinX + 1) * (z.Bounds.MaxY - z.Bounds.MinY + 1)
+}
+
+// Outranks reports whether the zone takes precedence over other where they
+// overlap: the higher priority wins, then the smaller. Neither outranks the
+// other on a tie, so the first listed wins.
+func (z *Zone) Outranks(other *Zone) bool {
+	return z.Priority > other.Priority ||
+		(z.Priority == other.Priority && z.area() < other.area())
+}
+
+// ZoneAt returns the zone at (x, y), or nil if none. Where zones overlap the
+// highest priority wins, then the smallest, then the first listed.
+func ZoneAt(zones []Zone, x, y int) *Zone {
+	var best *Zone
+	for i := range zones {
+		zone := &zones[i]
+		if !zone.Contains(x, y) {
+			continue
+		}
+		if best == nil || zone.Outranks(best) {
+			best = zone
+		}
+	}
+	return best
+}
+
+// WorldZone is a zone placed in the world. The embedded zone keeps the
+// bounds its chunk gave it; WorldBounds are the same tiles in world
+// coordinates.
+type WorldZone struct {
+	Zone
+	ChunkX      int    `json:"chunk_x"`
+	ChunkY      int    `json:"chunk_y"`
+	WorldBounds Bounds `json:"world_bounds"`
+}
+
+// ZoneQueryResponse is the result of a world zone query
+type ZoneQueryResponse struct {
+	Zones    []WorldZone `json:"zones"`
+	Distance *int        `json:"distance,omitempty"` // Tiles to the nearest zone, for nearest queries
 }
 
 // Bounds defines a rectangular area
@@ -67,6 +204,7 @@ type Bounds struct {
 // ViewportData represents the visible area around the player
 type ViewportData struct {
 	Tiles       [][]RenderedTile `json:"tiles"`
+	Position    Position         `json:"position"` // Player's map position
 	PlayerX     int              `json:"player_x"` // Relative to viewport
 	PlayerY     int              `json:"player_y"` // Relative to viewport
 	CurrentZone *Zone            `json:"current_zone,omitempty"`
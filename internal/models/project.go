@@ -1,5 +1,17 @@
 package models
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Project statuses
+const (
+	ProjectActive   = "active"   // Still being worked on or used
+	ProjectArchived = "archived" // Finished or abandoned, kept for the record
+)
+
 // Project represents a portfolio project
 type Project struct {
 	ID          string   `json:"id"`
@@ -10,9 +22,114 @@ type Project struct {
 	LiveURL     string   `json:"live_url,omitempty"`
 	Year        int      `json:"year"`
 	Featured    bool     `json:"featured"`
+	Status      string   `json:"status,omitempty"` // ProjectActive or ProjectArchived; active if empty
+	Links       []Link   `json:"links,omitempty"`  // Any further links, each labelled
+	Media       []Media  `json:"media,omitempty"`  // Images in the project's media directory
+	Body        string   `json:"-"`                // Markdown page, from projects/<id>.md beside projects.json
+}
+
+// Link is a labelled link from a project page
+type Link struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// Media is an image or screenshot of a project. File names a file in the
+// project's media directory; URL is where it's served, filled in on load.
+type Media struct {
+	File    string `json:"file"`
+	Caption string `json:"caption,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
+
+// ProjectLocation is where a project's structure stands in the world
+type ProjectLocation struct {
+	ChunkX int        `json:"chunk_x"`
+	ChunkY int        `json:"chunk_y"`
+	Name   string     `json:"name"`            // The structure's in-world name
+	Bounds Bounds     `json:"bounds"`          // World tiles the structure covers
+	Doors  []Position `json:"doors,omitempty"` // World tiles of its entrances
+}
+
+// ProjectResponse is a project with its page and where to find it, if it's
+// in the world
+type ProjectResponse struct {
+	Project
+	BodyHTML string           `json:"body_html,omitempty"` // The page as sanitized HTML
+	BodyText string           `json:"body_text,omitempty"` // The page as plain text
+	Location *ProjectLocation `json:"location,omitempty"`
+}
+
+// PathResponse is a walkable route to a project, both ends included
+type PathResponse struct {
+	ProjectID string     `json:"project_id"`
+	Steps     []Position `json:"steps"`
+	Length    int        `json:"length"` // Moves needed, one fewer than the steps
 }
 
 // ProjectList wraps the array of projects
 type ProjectList struct {
 	Projects []Project `json:"projects"`
 }
+
+// Validate checks the catalogue for projects that can't be served: missing
+// or repeated IDs, unknown statuses, unlabelled links and media outside the
+// project's directory
+func (l *ProjectList) Validate() error {
+	seen := make(map[string]bool, len(l.Projects))
+	for _, p := range l.Projects {
+		if p.ID == "" {
+			return fmt.Errorf("project %q has no id", p.Title)
+		}
+		if seen[p.ID] {
+			return fmt.Errorf("project id %q is used more than once", p.ID)
+		}
+		seen[p.ID] = true
+
+		switch p.Status {
+		case "", ProjectActive, ProjectArchived:
+		default:
+			return fmt.Errorf("project %q has status %q; expected %s or %s", p.ID, p.Status, ProjectActive, ProjectArchived)
+		}
+		for _, link := range p.Links {
+			if link.Label == "" || link.URL == "" {
+				return fmt.Errorf("project %q has a link without a label or url", p.ID)
+			}
+			if !safeLink(link.URL) {
+				return fmt.Errorf("project %q links to %q; expected an http, https or mailto url", p.ID, link.URL)
+			}
+		}
+		for _, u := range []string{p.GitHubURL, p.LiveURL} {
+			if u != "" && !safeLink(u) {
+				return fmt.Errorf("project %q links to %q; expected an http, https or mailto url", p.ID, u)
+			}
+		}
+		for _, m := range p.Media {
+			if m.File == "" || strings.ContainsAny(m.File, `/\`) || strings.HasPrefix(m.File, ".") {
+				return fmt.Errorf("project %q has media %q; expected a file name in its media directory", p.ID, m.File)
+			}
+		}
+	}
+	return nil
+}
+
+// safeLink reports whether a link is safe to put in a page: an http, https
+// or mailto URL, or a relative one, as markdown allows
+func safeLink(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+// TechCount is a technology and how many projects use it
+type TechCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
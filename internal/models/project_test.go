@@ -0,0 +1,35 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+// Only links a page can safely hold pass validation
+func TestValidateLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		project Project
+		err     string // Part of the error, or "" for none
+	}{
+		{"https", Project{ID: "p", Links: []Link{{"Docs", "https://example.com"}}}, ""},
+		{"mailto", Project{ID: "p", Links: []Link{{"Mail", "mailto:me@example.com"}}}, ""},
+		{"relative", Project{ID: "p", Links: []Link{{"Notes", "notes.html"}}}, ""},
+		{"javascript", Project{ID: "p", Links: []Link{{"Run", "javascript:alert(1)"}}}, "links to"},
+		{"javascript any case", Project{ID: "p", Links: []Link{{"Run", "JaVaScRiPt:alert(1)"}}}, "links to"},
+		{"data", Project{ID: "p", Links: []Link{{"Data", "data:text/html,x"}}}, "links to"},
+		{"control character", Project{ID: "p", Links: []Link{{"Run", "java\tscript:alert(1)"}}}, "links to"},
+		{"github url", Project{ID: "p", GitHubURL: "javascript:alert(1)"}, "links to"},
+		{"live url", Project{ID: "p", LiveURL: "vbscript:x"}, "links to"},
+		{"no label", Project{ID: "p", Links: []Link{{"", "https://example.com"}}}, "without a label"},
+	}
+	for _, tt := range tests {
+		err := (&ProjectList{Projects: []Project{tt.project}}).Validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -1,35 +1,54 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 
 	"dconn.dev/internal/models"
 )
 
-// GameService handles game logic
+// ErrPositionMismatch is returned when the client's idea of where the player
+// stands differs from the server's
+var ErrPositionMismatch = errors.New("position does not match the server's")
+
+// GameService handles game logic on the legacy map. Players' positions are
+// kept on sessions of a SessionService walking that map, so every move is
+// checked against the tiles the viewport is drawn from.
 type GameService struct {
 	mapService *MapService
+	sessions   *SessionService
 }
 
-// NewGameService creates a new GameService
-func NewGameService(ms *MapService) *GameService {
-	return &GameService{mapService: ms}
+// NewGameService creates a new GameService moving players of ss, which
+// should walk ms
+func NewGameService(ms *MapService, ss *SessionService) *GameService {
+	return &GameService{mapService: ms, sessions: ss}
 }
 
-// NewGame initializes a new game state at the spawn point
-func (s *GameService) NewGame() *models.GameState {
-	spawn := s.mapService.GetSpawnPoint()
-	return &models.GameState{
-		PlayerPosition: spawn,
-		ViewportSize:   15,
+// NewGame returns the game of the session with the given ID, starting a
+// session at the spawn point if there is none. The ID the game is kept under
+// is returned: id itself, or a new one if there's no such session.
+func (s *GameService) NewGame(id string) (string, *models.GameState, error) {
+	session, _, err := s.sessions.Start(id)
+	if err != nil {
+		return "", nil, err
 	}
+	return session.ID, &models.GameState{
+		PlayerPosition: session.Position,
+		ViewportSize:   15,
+	}, nil
 }
 
-// Move attempts to move the player in a direction
-// Returns the new position and any error
-func (s *GameService) Move(pos models.Position, direction string) (models.Position, error) {
-	newPos := pos
+// Move attempts to move a session's player one step in a direction.
+// claimed, if given, is where the client thinks the player stands. The
+// player's position is returned whether or not they moved.
+func (s *GameService) Move(id string, claimed *models.Position, direction string) (models.Position, error) {
+	session, err := s.session(id, claimed)
+	if err != nil {
+		return session.Position, err
+	}
 
+	newPos := session.Position
 	switch direction {
 	case "north", "w", "W":
 		newPos.Y--
@@ -40,12 +59,58 @@ func (s *GameService) Move(pos models.Position, direction string) (models.Positi
 	case "west", "a", "A":
 		newPos.X--
 	default:
-		return pos, fmt.Errorf("invalid direction: %s", direction)
+		return session.Position, fmt.Errorf("invalid direction: %s", direction)
 	}
 
-	if !s.mapService.IsWalkable(newPos) {
-		return pos, fmt.Errorf("cannot walk there")
+	return s.moveTo(id, session, newPos)
+}
+
+// MoveTowards moves a session's player along the shortest route to target,
+// taking as many steps as the time since their last move allows. claimed is
+// as for Move.
+func (s *GameService) MoveTowards(id string, claimed *models.Position, target models.Position) (models.Position, error) {
+	session, err := s.session(id, claimed)
+	if err != nil {
+		return session.Position, err
+	}
+	if session.Position == target {
+		return session.Position, nil
+	}
+	steps := s.sessions.StepsAllowed(session)
+	if steps == 0 {
+		return session.Position, ErrMoveTooSoon
 	}
 
-	return newPos, nil
+	route := s.sessions.FindPath(session.Position, target)
+	if route == nil {
+		return session.Position, fmt.Errorf("no route to %d,%d", target.X, target.Y)
+	}
+	return s.moveTo(id, session, route[min(steps, len(route)-1)])
+}
+
+// session returns a session, checking the client agrees where its player
+// stands. On a mismatch the session is still returned, so the caller can
+// report the right position.
+func (s *GameService) session(id string, claimed *models.Position) (*models.Session, error) {
+	session, err := s.sessions.Get(id)
+	if err != nil {
+		return &models.Session{}, err
+	}
+	if claimed != nil && *claimed != session.Position {
+		return session, ErrPositionMismatch
+	}
+	return session, nil
+}
+
+// moveTo records a session's player at pos, returning where they stand
+// afterwards
+func (s *GameService) moveTo(id string, session *models.Session, pos models.Position) (models.Position, error) {
+	updated, err := s.sessions.UpdatePosition(id, pos)
+	if updated != nil {
+		session = updated
+	}
+	if errors.Is(err, ErrRejectedPosition) {
+		return session.Position, fmt.Errorf("cannot walk there")
+	}
+	return session.Position, err
 }
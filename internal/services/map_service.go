@@ -7,11 +7,14 @@ import (
 // MapService handles map-related operations
 type MapService struct {
 	gameMap *models.GameMap
+	zones   *ZoneIndex
 }
 
 // NewMapService creates a new MapService
 func NewMapService(gm *models.GameMap) *MapService {
-	return &MapService{gameMap: gm}
+	zones := NewZoneIndex()
+	zones.Add(0, 0, 0, gm.Zones)
+	return &MapService{gameMap: gm, zones: zones}
 }
 
 // GetSpawnPoint returns the spawn position
@@ -25,9 +28,10 @@ func (s *MapService) GetViewport(center models.Position, width, height int) *mod
 	halfWidth := width / 2
 	halfHeight := height / 2
 	viewport := &models.ViewportData{
-		Tiles:   make([][]models.RenderedTile, height),
-		PlayerX: halfWidth,
-		PlayerY: halfHeight,
+		Tiles:    make([][]models.RenderedTile, height),
+		Position: center,
+		PlayerX:  halfWidth,
+		PlayerY:  halfHeight,
 	}
 
 	for y := 0; y < height; y++ {
@@ -85,20 +89,24 @@ func (s *MapService) IsWalkable(pos models.Position) bool {
 		return false
 	}
 
+	// The collision layer, when the map has one, knows about tiles whose
+	// glyph alone doesn't say (e.g. a walkable bridge over water)
+	if blocked, ok := s.gameMap.Layers.Blocked(pos.X, pos.Y); ok {
+		return !blocked
+	}
+
 	tile := s.getTileAt(pos.X, pos.Y)
 	return tile.Walkable
 }
 
-// GetZoneAt returns the zone at a specific position, or nil if none
+// GetZoneAt returns the zone at a specific position, or nil if none. Where
+// zones overlap the highest priority one wins.
 func (s *MapService) GetZoneAt(pos models.Position) *models.Zone {
-	for i := range s.gameMap.Zones {
-		zone := &s.gameMap.Zones[i]
-		if pos.X >= zone.Bounds.MinX && pos.X <= zone.Bounds.MaxX &&
-			pos.Y >= zone.Bounds.MinY && pos.Y <= zone.Bounds.MaxY {
-			return zone
-		}
+	zones := s.zones.At(pos.X, pos.Y)
+	if len(zones) == 0 {
+		return nil
 	}
-	return nil
+	return &zones[0].Zone
 }
 
 // GetAllZones returns all zones on the map
@@ -108,12 +116,12 @@ func (s *MapService) GetAllZones() []models.Zone {
 
 // FullMapData is the complete map data for client-side rendering
 type FullMapData struct {
-	Width           int                    `json:"width"`
-	Height          int                    `json:"height"`
-	Spawn           models.Position        `json:"spawn"`
+	Width           int                     `json:"width"`
+	Height          int                     `json:"height"`
+	Spawn           models.Position         `json:"spawn"`
 	Tiles           [][]models.RenderedTile `json:"tiles"`
-	TileDefinitions map[string]models.Tile `json:"tile_definitions"`
-	Zones           []models.Zone          `json:"zones"`
+	TileDefinitions map[string]models.Tile  `json:"tile_definitions"`
+	Zones           []models.Zone           `json:"zones"`
 }
 
 // GetFullMapData returns the entire map for client-side caching
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"dconn.dev/internal/models"
+)
+
+// MaxPerPage caps how many projects a single page of results can hold
+const MaxPerPage = 100
+
+// ProjectQuery filters, sorts and pages the project catalogue. Zero values
+// leave that part of the query out.
+type ProjectQuery struct {
+	Search   string   // Words that must all appear in the title or description
+	Tech     []string // Technologies a project must all use, any case
+	Year     int
+	Featured *bool
+	Sort     string // "title", "year" or "relevance", "-" first to reverse; catalogue order by default
+	Page     int    // 1-based; needs PerPage
+	PerPage  int    // 0 for everything
+}
+
+// ProjectPage is one page of query results
+type ProjectPage struct {
+	Projects []models.Project
+	Total    int // Matches across all pages
+}
+
+// projectIndex is the lookup structure for the catalogue, rebuilt whenever
+// the projects change
+type projectIndex struct {
+	byID  map[string]int
+	words map[string][]projectHit // Search word to the projects it appears in
+	tech  map[string][]int        // Lower-cased technology to projects using it
+	techs []models.TechCount      // Every technology, most used first
+}
+
+// projectHit is a search word appearing in a project, and how strongly
+type projectHit struct {
+	project int
+	weight  int
+}
+
+// Words in a title count for more than words in a description
+const (
+	titleWeight       = 3
+	descriptionWeight = 1
+)
+
+// buildProjectIndex indexes a catalogue
+func buildProjectIndex(projects []models.Project) *projectIndex {
+	idx := &projectIndex{
+		byID:  make(map[string]int, len(projects)),
+		words: make(map[string][]projectHit),
+		tech:  make(map[string][]int),
+	}
+
+	names := make(map[string]string) // Lower-cased to first spelling seen
+	for i, p := range projects {
+		idx.byID[p.ID] = i
+
+		weights := make(map[string]int)
+		for _, word := range searchWords(p.Title) {
+			weights[word] += titleWeight
+		}
+		for _, word := range searchWords(p.Description) {
+			weights[word] += descriptionWeight
+		}
+		for word, weight := range weights {
+			idx.words[word] = append(idx.words[word], projectHit{i, weight})
+		}
+
+		for _, t := range p.TechStack {
+			key := strings.ToLower(t)
+			if _, ok := names[key]; !ok {
+				names[key] = t
+			}
+			idx.tech[key] = append(idx.tech[key], i)
+		}
+	}
+
+	for key, users := range idx.tech {
+		idx.techs = append(idx.techs, models.TechCount{Name: names[key], Count: len(users)})
+	}
+	sort.Slice(idx.techs, func(i, j int) bool {
+		a, b := idx.techs[i], idx.techs[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+
+	return idx
+}
+
+// searchWords splits text into lower-cased words for searching
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// search scores the projects matching every word of the query. A query word
+// matches any indexed word it begins, so "compil" finds "compiler".
+func (idx *projectIndex) search(query string) map[int]int {
+	var scores map[int]int
+	for _, term := range searchWords(query) {
+		matched := make(map[int]int)
+		for word, hits := range idx.words {
+			if !strings.HasPrefix(word, term) {
+				continue
+			}
+			for _, hit := range hits {
+				matched[hit.project] += hit.weight
+			}
+		}
+
+		if scores == nil {
+			scores = matched
+			continue
+		}
+		for i := range scores {
+			if extra, ok := matched[i]; ok {
+				scores[i] += extra
+			} else {
+				delete(scores, i)
+			}
+		}
+	}
+	return scores
+}
+
+// query runs a ProjectQuery against the indexed projects
+func (idx *projectIndex) query(projects []models.Project, q ProjectQuery) (*ProjectPage, error) {
+	if q.PerPage < 0 || q.PerPage > MaxPerPage {
+		return nil, fmt.Errorf("per_page must be between 1 and %d", MaxPerPage)
+	}
+	if q.Page < 0 {
+		return nil, fmt.Errorf("page must be 1 or more")
+	}
+	if q.Page > 0 && q.PerPage == 0 {
+		return nil, fmt.Errorf("page needs per_page")
+	}
+
+	var scores map[int]int
+	if strings.TrimSpace(q.Search) != "" {
+		scores = idx.search(q.Search)
+	}
+
+	// Projects using every requested technology
+	wanted := 0
+	var uses map[int]int
+	for _, t := range q.Tech {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		wanted++
+		if uses == nil {
+			uses = make(map[int]int)
+		}
+		for _, i := range idx.tech[strings.ToLower(t)] {
+			uses[i]++
+		}
+	}
+
+	var matches []int
+	for i, p := range projects {
+		if scores != nil {
+			if _, ok := scores[i]; !ok {
+				continue
+			}
+		}
+		if uses != nil && uses[i] < wanted {
+			continue
+		}
+		if q.Year != 0 && p.Year != q.Year {
+			continue
+		}
+		if q.Featured != nil && p.Featured != *q.Featured {
+			continue
+		}
+		matches = append(matches, i)
+	}
+
+	key, desc := strings.CutPrefix(q.Sort, "-")
+	var less func(a, b int) bool
+	switch key {
+	case "":
+		if scores != nil {
+			less = func(a, b int) bool { return scores[a] > scores[b] }
+		}
+	case "relevance":
+		less = func(a, b int) bool { return scores[a] > scores[b] }
+	case "title":
+		less = func(a, b int) bool {
+			return strings.ToLower(projects[a].Title) < strings.ToLower(projects[b].Title)
+		}
+	case "year":
+		less = func(a, b int) bool { return projects[a].Year < projects[b].Year }
+	default:
+		return nil, fmt.Errorf("unknown sort %q; expected title, year or relevance", q.Sort)
+	}
+	if less != nil {
+		sort.SliceStable(matches, func(i, j int) bool {
+			if desc {
+				return less(matches[j], matches[i])
+			}
+			return less(matches[i], matches[j])
+		})
+	}
+
+	page := &ProjectPage{Total: len(matches), Projects: []models.Project{}}
+	if q.PerPage > 0 {
+		// Pages past the last are empty. Checking before multiplying keeps
+		// a huge page number from overflowing.
+		skip := max(q.Page, 1) - 1
+		if skip >= (len(matches)+q.PerPage-1)/q.PerPage {
+			matches = nil
+		} else {
+			start := skip * q.PerPage
+			matches = matches[start:min(start+q.PerPage, len(matches))]
+		}
+	}
+	for _, i := range matches {
+		page.Projects = append(page.Projects, projects[i])
+	}
+	return page, nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"dconn.dev/internal/models"
+)
+
+// Pages split the matches in order, and pages past the last are empty,
+// however far past
+func TestQueryPaging(t *testing.T) {
+	var projects []models.Project
+	for i := 0; i < 7; i++ {
+		projects = append(projects, models.Project{ID: fmt.Sprintf("p%d", i), Title: fmt.Sprintf("Project %d", i)})
+	}
+	idx := buildProjectIndex(projects)
+
+	tests := []struct {
+		page, perPage int
+		want          []string
+	}{
+		{0, 0, []string{"p0", "p1", "p2", "p3", "p4", "p5", "p6"}},
+		{1, 3, []string{"p0", "p1", "p2"}},
+		{2, 3, []string{"p3", "p4", "p5"}},
+		{3, 3, []string{"p6"}},
+		{4, 3, nil},
+		{math.MaxInt, 100, nil},
+		{100000000000000000, 100, nil},
+	}
+	for _, tt := range tests {
+		page, err := idx.query(projects, ProjectQuery{Page: tt.page, PerPage: tt.perPage})
+		if err != nil {
+			t.Errorf("page %d of %d: %v", tt.page, tt.perPage, err)
+			continue
+		}
+		var got []string
+		for _, p := range page.Projects {
+			got = append(got, p.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) || page.Total != len(projects) {
+			t.Errorf("page %d of %d = %v (total %d), want %v (total %d)", tt.page, tt.perPage, got, page.Total, tt.want, len(projects))
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"dconn.dev/internal/models"
+)
+
+// maxRouteTiles bounds how many tiles a route search visits before giving up
+const maxRouteTiles = 250000
+
+// addLocations records the projects a chunk's zones place, with their
+// doors. Bounds and doors are converted to world tiles.
+func (ws *WorldService) addLocations(x, y int, chunk *models.Chunk) {
+	originX, originY := x*ws.world.ChunkSize, y*ws.world.ChunkSize
+	found := make(map[string]*models.ProjectLocation)
+	var order []string
+
+	for i := range chunk.Zones {
+		zone := &chunk.Zones[i]
+		if zone.ProjectID == "" || zone.Kind() != models.ZoneProject {
+			continue
+		}
+		if _, ok := found[zone.ProjectID]; ok {
+			continue // An exhibit within the structure
+		}
+		found[zone.ProjectID] = &models.ProjectLocation{
+			ChunkX: x,
+			ChunkY: y,
+			Name:   zone.Name,
+			Bounds: models.Bounds{
+				MinX: zone.Bounds.MinX + originX,
+				MaxX: zone.Bounds.MaxX + originX,
+				MinY: zone.Bounds.MinY + originY,
+				MaxY: zone.Bounds.MaxY + originY,
+			},
+		}
+		order = append(order, zone.ProjectID)
+	}
+
+	for i := range chunk.Zones {
+		zone := &chunk.Zones[i]
+		if p, ok := found[zone.ProjectID]; ok && zone.Kind() == models.ZoneInterior {
+			p.Doors = append(p.Doors, models.Position{
+				X: (zone.Bounds.MinX+zone.Bounds.MaxX)/2 + originX,
+				Y: (zone.Bounds.MinY+zone.Bounds.MaxY)/2 + originY,
+			})
+		}
+	}
+
+	for _, id := range order {
+		ws.locations[id] = append(ws.locations[id], found[id])
+	}
+}
+
+// loadAll loads every chunk in the manifest, for lookups across the world
+func (ws *WorldService) loadAll() {
+	for key := range ws.world.Chunks {
+		var x, y int
+		if _, err := fmt.Sscanf(key, "%d,%d", &x, &y); err == nil {
+			ws.loadChunk(x, y)
+		}
+	}
+}
+
+// ProjectLocation returns where a project stands in the world, or nil if it
+// isn't placed. A project placed more than once is found in the first chunk
+// by row, then column.
+func (ws *WorldService) ProjectLocation(id string) *models.ProjectLocation {
+	ws.loadAll()
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	placed := ws.locations[id]
+	if len(placed) == 0 {
+		return nil
+	}
+	sort.Slice(placed, func(i, j int) bool {
+		if placed[i].ChunkY != placed[j].ChunkY {
+			return placed[i].ChunkY < placed[j].ChunkY
+		}
+		return placed[i].ChunkX < placed[j].ChunkX
+	})
+	loc := *placed[0]
+	return &loc
+}
+
+// Placements returns every project placement in the world
+func (ws *WorldService) Placements() []models.Placement {
+	ws.loadAll()
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	var out []models.Placement
+	for id, placed := range ws.locations {
+		for _, loc := range placed {
+			out = append(out, models.Placement{ProjectID: id, Name: loc.Name, ChunkX: loc.ChunkX, ChunkY: loc.ChunkY})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ProjectID < out[j].ProjectID })
+	return out
+}
+
+// PathToProject returns the shortest walkable route from a world tile to a
+// project: to one of its doors, or into its structure if it has none
+func (ws *WorldService) PathToProject(from models.Position, id string) ([]models.Position, error) {
+	loc := ws.ProjectLocation(id)
+	if loc == nil {
+		return nil, fmt.Errorf("project %s is not placed in the world", id)
+	}
+	if !ws.IsWalkable(from.X, from.Y) {
+		return nil, fmt.Errorf("start %d,%d is not walkable", from.X, from.Y)
+	}
+
+	goal := func(p models.Position) bool {
+		b := loc.Bounds
+		return p.X >= b.MinX && p.X <= b.MaxX && p.Y >= b.MinY && p.Y <= b.MaxY
+	}
+	if len(loc.Doors) > 0 {
+		goal = func(p models.Position) bool {
+			for _, door := range loc.Doors {
+				if p == door {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	path := ws.FindPath(from, goal)
+	if path == nil {
+		return nil, fmt.Errorf("no walkable route from %d,%d to %s", from.X, from.Y, id)
+	}
+	return path, nil
+}
+
+// FindPath returns the shortest walkable route from a world tile to the
+// nearest tile goal accepts, both ends included, or nil if there is none
+func (ws *WorldService) FindPath(from models.Position, goal func(models.Position) bool) []models.Position {
+	return findPath(from, goal, func(p models.Position) bool { return ws.IsWalkable(p.X, p.Y) })
+}
+
+// findPath searches breadth first from a tile for the nearest one goal
+// accepts, stepping only onto tiles walkable accepts, and returns the route
+// with both ends included, or nil if there is none within maxRouteTiles
+func findPath(from models.Position, goal, walkable func(models.Position) bool) []models.Position {
+	steps := []models.Position{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}}
+
+	came := map[models.Position]models.Position{from: from}
+	queue := []models.Position{from}
+	for len(queue) > 0 && len(came) < maxRouteTiles {
+		current := queue[0]
+		queue = queue[1:]
+
+		if goal(current) {
+			var path []models.Position
+			for p := current; p != from; p = came[p] {
+				path = append(path, p)
+			}
+			path = append(path, from)
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+
+		for _, step := range steps {
+			next := models.Position{X: current.X + step.X, Y: current.Y + step.Y}
+			if _, seen := came[next]; seen || !walkable(next) {
+				continue
+			}
+			came[next] = current
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
@@ -2,31 +2,107 @@ package services
 
 import (
 	"fmt"
+	"path"
+	"sync"
 
+	"dconn.dev/internal/markdown"
 	"dconn.dev/internal/models"
 )
 
+// MediaURLPrefix is where project media directories are served
+const MediaURLPrefix = "/media"
+
+// projectPage is a project's markdown page, rendered
+type projectPage struct {
+	html, text string
+}
+
 // ProjectService handles project-related operations
 type ProjectService struct {
+	mu       sync.RWMutex
 	projects *models.ProjectList
+	index    *projectIndex
+	pages    map[string]projectPage
 }
 
 // NewProjectService creates a new ProjectService
 func NewProjectService(projects *models.ProjectList) *ProjectService {
-	return &ProjectService{projects: projects}
+	s := &ProjectService{}
+	s.Reload(projects)
+	return s
+}
+
+// Reload replaces the catalogue, rebuilding its index and rendering its
+// pages. Projects without a status are taken as active, and media get the
+// URLs they're served from.
+func (s *ProjectService) Reload(projects *models.ProjectList) {
+	prepared := &models.ProjectList{Projects: make([]models.Project, len(projects.Projects))}
+	pages := make(map[string]projectPage)
+	for i, p := range projects.Projects {
+		base := path.Join(MediaURLPrefix, p.ID)
+		if p.Status == "" {
+			p.Status = models.ProjectActive
+		}
+		p.Media = append([]models.Media(nil), p.Media...)
+		for j := range p.Media {
+			p.Media[j].URL = path.Join(base, p.Media[j].File)
+		}
+		if p.Body != "" {
+			html, text := markdown.Render(p.Body, base)
+			pages[p.ID] = projectPage{html, text}
+		}
+		prepared.Projects[i] = p
+	}
+	index := buildProjectIndex(prepared.Projects)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects = prepared
+	s.index = index
+	s.pages = pages
 }
 
 // GetAll returns all projects
 func (s *ProjectService) GetAll() []models.Project {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.projects.Projects
 }
 
 // GetByID returns a specific project by ID
 func (s *ProjectService) GetByID(id string) (*models.Project, error) {
-	for i := range s.projects.Projects {
-		if s.projects.Projects[i].ID == id {
-			return &s.projects.Projects[i], nil
-		}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i, ok := s.index.byID[id]; ok {
+		return &s.projects.Projects[i], nil
 	}
 	return nil, fmt.Errorf("project not found: %s", id)
 }
+
+// GetPage returns a project with its page rendered as HTML and as text
+func (s *ProjectService) GetPage(id string) (*models.ProjectResponse, error) {
+	project, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	page := s.pages[id]
+	return &models.ProjectResponse{Project: *project, BodyHTML: page.html, BodyText: page.text}, nil
+}
+
+// Query searches, filters, sorts and pages the projects
+func (s *ProjectService) Query(q ProjectQuery) (*ProjectPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.query(s.projects.Projects, q)
+}
+
+// GetTech returns every technology in the catalogue with how many projects
+// use it, most used first
+func (s *ProjectService) GetTech() []models.TechCount {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.techs
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dconn.dev/internal/models"
+)
+
+// maxPositionJump is the furthest, in steps, a reported position may be
+// from the last confirmed one. Clients report every few moves, so anything
+// further is a teleport.
+const maxPositionJump = 12
+
+// moveJitter is how much sooner than the move delay allows a step may
+// arrive, so reports bunched up by the network aren't refused
+const moveJitter = 25 * time.Millisecond
+
+// maxSettings bounds how many settings a session can hold
+const maxSettings = 32
+
+// SessionTTL is how long a session is kept after it was last updated
+const SessionTTL = 90 * 24 * time.Hour
+
+var (
+	// ErrRejectedPosition is returned when a reported position can't have
+	// been reached from the last confirmed one
+	ErrRejectedPosition = errors.New("position rejected")
+
+	// ErrMoveTooSoon is returned when a position is further from the last
+	// confirmed one than the move delay allows in the time since
+	ErrMoveTooSoon = errors.New("moving too fast")
+)
+
+// ground is what players walk on: the chunk world, or the legacy single map
+type ground interface {
+	SpawnPoint() models.Position
+	IsWalkable(x, y int) bool
+}
+
+// legacyGround lets players walk the legacy single map
+type legacyGround struct {
+	ms *MapService
+}
+
+func (g legacyGround) SpawnPoint() models.Position {
+	return g.ms.GetSpawnPoint()
+}
+
+func (g legacyGround) IsWalkable(x, y int) bool {
+	return g.ms.IsWalkable(models.Position{X: x, Y: y})
+}
+
+// SessionService keeps visitors' progress in a SessionStore. It's the one
+// record of where each player stands: every way of moving goes through
+// UpdatePosition, which only accepts walkable steps from the last confirmed
+// position at no more than one per move delay.
+type SessionService struct {
+	mu        sync.Mutex // Serialises read-modify-write of sessions
+	store     SessionStore
+	ground    ground
+	world     *WorldService // nil on the legacy map: no zones, teleporters or fog
+	moveDelay time.Duration
+	pruned    time.Time
+}
+
+// NewSessionService creates a new SessionService whose players walk the
+// chunk world, or the legacy map when ws is nil, taking one step per
+// moveDelay
+func NewSessionService(store SessionStore, ws *WorldService, ms *MapService, moveDelay time.Duration) *SessionService {
+	s := &SessionService{store: store, world: ws, moveDelay: moveDelay}
+	if ws != nil {
+		s.ground = ws
+	} else {
+		s.ground = legacyGround{ms}
+	}
+	return s
+}
+
+// validSessionID reports whether id looks like one newSessionID made
+func validSessionID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// newSessionID returns a random session ID
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to create session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Start resumes the session with the given ID, or starts a new one at the
+// spawn point if there is none. resumed reports which.
+func (s *SessionService) Start(id string) (session *models.Session, resumed bool, err error) {
+	if id != "" {
+		session, err = s.store.Get(id)
+		if err == nil {
+			return session, true, nil
+		}
+		if !errors.Is(err, ErrSessionNotFound) {
+			return nil, false, err
+		}
+	}
+
+	s.prune()
+	id, err = newSessionID()
+	if err != nil {
+		return nil, false, err
+	}
+	now := time.Now().UTC()
+	session = &models.Session{
+		ID:        id,
+		Position:  s.ground.SpawnPoint(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.visit(session, []models.Position{session.Position})
+	if err := s.store.Save(session); err != nil {
+		return nil, false, err
+	}
+	return session, false, nil
+}
+
+// prune forgets sessions not updated for SessionTTL, checking at most once
+// an hour. A failed prune is tried again on the next new session.
+func (s *SessionService) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.pruned) < time.Hour {
+		return
+	}
+	if err := s.store.Prune(now.Add(-SessionTTL)); err == nil {
+		s.pruned = now
+	}
+}
+
+// Get returns a session
+func (s *SessionService) Get(id string) (*models.Session, error) {
+	return s.store.Get(id)
+}
+
+// UpdatePosition records where the player now stands. A position that
+// can't be walked to in maxPositionJump steps from the last confirmed one,
+// and isn't where a teleporter there leads, is rejected with
+// ErrRejectedPosition; one that takes more steps than the move delay allows
+// since the last move is rejected with ErrMoveTooSoon. Either way the
+// session is returned unchanged so the client can put the player back.
+func (s *SessionService) UpdatePosition(id string, pos models.Position) (*models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	last := session.Position
+	if pos == last {
+		return session, nil
+	}
+	walked := s.route(last, pos)
+	if walked == nil {
+		if !s.teleportsTo(last, pos) || !s.ground.IsWalkable(pos.X, pos.Y) {
+			return session, ErrRejectedPosition
+		}
+		walked = []models.Position{pos}
+	}
+	if len(walked) > s.StepsAllowed(session) {
+		return session, ErrMoveTooSoon
+	}
+
+	now := time.Now().UTC()
+	session.Position = pos
+	session.LastMove = now
+	session.UpdatedAt = now
+	s.visit(session, walked)
+	if err := s.store.Save(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// StepsAllowed returns how many steps a session's player may take now, given
+// the move delay since their last move
+func (s *SessionService) StepsAllowed(session *models.Session) int {
+	if session.LastMove.IsZero() || s.moveDelay <= 0 {
+		return maxPositionJump
+	}
+	elapsed := time.Since(session.LastMove) + moveJitter
+	return min(int(elapsed/s.moveDelay), maxPositionJump)
+}
+
+// route returns the tiles a player walked between two reported positions,
+// without the first: the shortest walkable route, if there is one of at
+// most maxPositionJump steps. nil if there isn't.
+func (s *SessionService) route(from, to models.Position) []models.Position {
+	near := func(p models.Position) bool {
+		return abs(p.X-from.X)+abs(p.Y-from.Y) <= maxPositionJump && s.ground.IsWalkable(p.X, p.Y)
+	}
+	path := findPath(from, func(p models.Position) bool { return p == to }, near)
+	if path == nil || len(path)-1 > maxPositionJump {
+		return nil
+	}
+	return path[1:]
+}
+
+// FindPath returns the shortest walkable route between two positions on the
+// ground players walk, both ends included, or nil if there is none
+func (s *SessionService) FindPath(from, to models.Position) []models.Position {
+	if !s.ground.IsWalkable(to.X, to.Y) {
+		return nil
+	}
+	walkable := func(p models.Position) bool { return s.ground.IsWalkable(p.X, p.Y) }
+	return findPath(from, func(p models.Position) bool { return p == to }, walkable)
+}
+
+// Fog returns what a session has explored of the world
+func (s *SessionService) Fog(id string) (*models.FogResponse, error) {
+	session, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.world == nil {
+		return nil, errors.New("there is no chunk world to explore")
+	}
+	fog := session.Fog
+	if fog == nil {
+		fog = make(map[string][]string)
+	}
+	return &models.FogResponse{ChunkSize: s.world.world.ChunkSize, Chunks: fog}, nil
+}
+
+// teleportsTo reports whether a teleporter at from sends the player to to
+func (s *SessionService) teleportsTo(from, to models.Position) bool {
+	if s.world == nil {
+		return false
+	}
+	for _, zone := range s.world.ZonesAt(from.X, from.Y) {
+		if zone.Kind() == models.ZoneTeleporter && zone.Metadata["to"] == fmt.Sprintf("%d,%d", to.X, to.Y) {
+			return true
+		}
+	}
+	return false
+}
+
+// visit records the chunk and zones at the session's position as explored
+// and visited, and what can be seen from each tile walked as explored. The
+// legacy map has no chunks or fog, so there's nothing to record.
+func (s *SessionService) visit(session *models.Session, walked []models.Position) {
+	if s.world == nil {
+		return
+	}
+
+	pos := session.Position
+	chunkX, _ := splitCoord(pos.X, s.world.world.ChunkSize)
+	chunkY, _ := splitCoord(pos.Y, s.world.world.ChunkSize)
+	session.ExploredChunks = appendNew(session.ExploredChunks, fmt.Sprintf("%d,%d", chunkX, chunkY))
+
+	for _, zone := range s.world.ZonesAt(pos.X, pos.Y) {
+		session.VisitedZones = appendNew(session.VisitedZones, zoneKey(&zone))
+	}
+
+	for _, step := range walked {
+		s.world.reveal(session, s.world.VisibleFrom(step))
+	}
+}
+
+// zoneKey names a zone across the world: its chunk and its name
+func zoneKey(z *models.WorldZone) string {
+	return strconv.Itoa(z.ChunkX) + "," + strconv.Itoa(z.ChunkY) + ":" + z.Name
+}
+
+// appendNew appends s to list unless it's already there
+func appendNew(list []string, s string) []string {
+	for _, have := range list {
+		if have == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// UpdateSettings merges settings into the session's. An empty value removes
+// a setting.
+func (s *SessionService) UpdateSettings(id string, settings map[string]string) (*models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Settings == nil {
+		session.Settings = make(map[string]string)
+	}
+	for k, v := range settings {
+		if k = strings.TrimSpace(k); k == "" {
+			continue
+		}
+		if v == "" {
+			delete(session.Settings, k)
+		} else {
+			session.Settings[k] = v
+		}
+	}
+	if len(session.Settings) > maxSettings {
+		return nil, fmt.Errorf("at most %d settings can be saved", maxSettings)
+	}
+
+	session.UpdatedAt = time.Now().UTC()
+	if err := s.store.Save(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// End forgets a session
+func (s *SessionService) End(id string) error {
+	return s.store.Delete(id)
+}
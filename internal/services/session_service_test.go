@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dconn.dev/internal/models"
+)
+
+// walledMap is a legacy map split by a wall at x = 5 with one gap, at the
+// bottom. Crossing it near the top takes far more than maxPositionJump steps.
+func walledMap() *MapService {
+	rows := make([][]string, 10)
+	for y := range rows {
+		row := strings.Split(strings.Repeat(".", 12), "")
+		if y < 9 {
+			row[5] = "#"
+		}
+		rows[y] = row
+	}
+	return NewMapService(&models.GameMap{
+		Width:  12,
+		Height: 10,
+		Tiles:  rows,
+		TileDefinitions: map[string]models.Tile{
+			".": {Character: ".", Walkable: true},
+			"#": {Character: "#"},
+		},
+		Spawn: models.Position{X: 3, Y: 0},
+	})
+}
+
+// startAt starts a session on the walled map and backdates its last move by
+// ago, as if the player had stood still that long
+func startAt(t *testing.T, s *SessionService, ago time.Duration) *models.Session {
+	t.Helper()
+	session, _, err := s.Start("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.LastMove = time.Now().Add(-ago)
+	if err := s.store.Save(session); err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestUpdatePosition(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	tests := []struct {
+		name string
+		to   models.Position
+		ago  time.Duration // Since the last move
+		want error
+	}{
+		{"one step", models.Position{X: 4, Y: 0}, time.Second, nil},
+		{"several steps after waiting", models.Position{X: 3, Y: 8}, time.Second, nil},
+		{"round a corner", models.Position{X: 0, Y: 3}, time.Second, nil},
+		{"through the wall", models.Position{X: 7, Y: 0}, time.Second, ErrRejectedPosition},
+		{"into the wall", models.Position{X: 5, Y: 0}, time.Second, ErrRejectedPosition},
+		{"off the map", models.Position{X: 3, Y: -1}, time.Second, ErrRejectedPosition},
+		{"further than a jump", models.Position{X: 11, Y: 9}, time.Hour, ErrRejectedPosition},
+		{"more steps than the delay allows", models.Position{X: 3, Y: 5}, 2 * delay, ErrMoveTooSoon},
+		{"as many steps as the delay allows", models.Position{X: 3, Y: 5}, 5 * delay, nil},
+	}
+	for _, tt := range tests {
+		s := NewSessionService(NewMemoryStore(), nil, walledMap(), delay)
+		session := startAt(t, s, tt.ago)
+
+		got, err := s.UpdatePosition(session.ID, tt.to)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.want)
+			continue
+		}
+		want := tt.to
+		if tt.want != nil {
+			want = session.Position // Refused moves leave the player put
+		}
+		if got.Position != want {
+			t.Errorf("%s: player at %v, want %v", tt.name, got.Position, want)
+		}
+		if stored, _ := s.Get(session.ID); stored.Position != want {
+			t.Errorf("%s: stored player at %v, want %v", tt.name, stored.Position, want)
+		}
+	}
+}
+
+// A move spends the step budget: straight after one, the next has to wait
+func TestUpdatePositionSpendsBudget(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	s := NewSessionService(NewMemoryStore(), nil, walledMap(), delay)
+	session := startAt(t, s, time.Second)
+
+	if _, err := s.UpdatePosition(session.ID, models.Position{X: 3, Y: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdatePosition(session.ID, models.Position{X: 3, Y: 6}); !errors.Is(err, ErrMoveTooSoon) {
+		t.Errorf("second move straight away: got error %v, want %v", err, ErrMoveTooSoon)
+	}
+
+	time.Sleep(2 * delay)
+	if _, err := s.UpdatePosition(session.ID, models.Position{X: 3, Y: 6}); err != nil {
+		t.Errorf("second move after waiting: %v", err)
+	}
+}
+
+// Starting a session drops those idle for longer than SessionTTL
+func TestStartPrunesExpired(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewSessionService(store, nil, walledMap(), 0)
+
+	now := time.Now().UTC()
+	stale := &models.Session{ID: strings.Repeat("a", 32), UpdatedAt: now.Add(-SessionTTL - time.Hour)}
+	fresh := &models.Session{ID: strings.Repeat("b", 32), UpdatedAt: now.Add(-SessionTTL + time.Hour)}
+	for _, session := range []*models.Session{stale, fresh} {
+		if err := store.Save(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := s.Start(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(stale.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expired session: got error %v, want %v", err, ErrSessionNotFound)
+	}
+	if _, err := s.Get(fresh.ID); err != nil {
+		t.Errorf("live session: %v", err)
+	}
+}
+
+// A session comes back from a FileStore as it went in, and pruning removes
+// only expired files
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	session := &models.Session{
+		ID:             strings.Repeat("c", 32),
+		Position:       models.Position{X: -3, Y: 7},
+		LastMove:       now,
+		ExploredChunks: []string{"0,0", "-1,0"},
+		VisitedZones:   []string{"0,0:Tower"},
+		Settings:       map[string]string{"palette": "plain"},
+		Fog:            map[string][]string{"0,0": {"#.", ".#"}},
+		CreatedAt:      now.Add(-time.Hour),
+		UpdatedAt:      now,
+	}
+	if err := store.Save(session); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Get(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, session) {
+		t.Errorf("round trip =\n%+v\nwant\n%+v", got, session)
+	}
+
+	// Reopening the directory finds it again
+	reopened, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get(session.ID); err != nil {
+		t.Errorf("after reopening: %v", err)
+	}
+
+	if _, err := store.Get("../escape"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("path outside the directory: got error %v, want %v", err, ErrSessionNotFound)
+	}
+
+	if err := store.Prune(now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("after pruning: got error %v, want %v", err, ErrSessionNotFound)
+	}
+}
+
+// A full MemoryStore makes room by forgetting the least recently updated
+// session
+func TestMemoryStoreCap(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Now()
+	for i := 0; i < maxMemorySessions; i++ {
+		id := fmt.Sprintf("%032x", i)
+		if err := store.Save(&models.Session{ID: id, UpdatedAt: start.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldest := fmt.Sprintf("%032x", 0)
+	newest := strings.Repeat("f", 32)
+	if err := store.Save(&models.Session{ID: newest, UpdatedAt: start.Add(time.Hour * 24)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.sessions) != maxMemorySessions {
+		t.Errorf("store holds %d sessions, want %d", len(store.sessions), maxMemorySessions)
+	}
+	if _, err := store.Get(oldest); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("least recently updated session: got error %v, want %v", err, ErrSessionNotFound)
+	}
+	if _, err := store.Get(newest); err != nil {
+		t.Errorf("new session: %v", err)
+	}
+}
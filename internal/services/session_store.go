@@ -0,0 +1,240 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"dconn.dev/internal/models"
+)
+
+// ErrSessionNotFound is returned by a SessionStore for an unknown ID
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore keeps sessions between requests. Implementations must be
+// safe for concurrent use, and hand out copies so callers can't change a
+// stored session without saving it.
+type SessionStore interface {
+	Get(id string) (*models.Session, error)
+	Save(session *models.Session) error
+	Delete(id string) error
+
+	// Prune deletes sessions last updated before cutoff
+	Prune(cutoff time.Time) error
+}
+
+// copySession copies a session deeply enough that neither copy shares
+// slices or maps with the other
+func copySession(s *models.Session) *models.Session {
+	c := *s
+	c.ExploredChunks = append([]string(nil), s.ExploredChunks...)
+	c.VisitedZones = append([]string(nil), s.VisitedZones...)
+	if s.Settings != nil {
+		c.Settings = make(map[string]string, len(s.Settings))
+		for k, v := range s.Settings {
+			c.Settings[k] = v
+		}
+	}
+	if s.Fog != nil {
+		c.Fog = make(map[string][]string, len(s.Fog))
+		for k, mask := range s.Fog {
+			c.Fog[k] = append([]string(nil), mask...)
+		}
+	}
+	return &c
+}
+
+// maxMemorySessions caps how many sessions a MemoryStore holds. Every new
+// visitor starts one, so without a cap a flood of cookie-less requests could
+// fill memory faster than idle sessions expire.
+const maxMemorySessions = 10000
+
+// MemoryStore keeps sessions in memory, losing them on restart. When it's
+// full, saving a new session forgets the least recently updated one.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]*models.Session
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]*models.Session)}
+}
+
+// Get returns a copy of a stored session
+func (m *MemoryStore) Get(id string) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return copySession(s), nil
+}
+
+// Save stores a copy of a session, making room for it if the store is full
+func (m *MemoryStore) Save(session *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sessions[session.ID]; !ok && len(m.sessions) >= maxMemorySessions {
+		m.evictOldest()
+	}
+	m.sessions[session.ID] = copySession(session)
+	return nil
+}
+
+// evictOldest forgets the least recently updated session. Callers hold m.mu.
+func (m *MemoryStore) evictOldest() {
+	var oldest *models.Session
+	for _, s := range m.sessions {
+		if oldest == nil || s.UpdatedAt.Before(oldest.UpdatedAt) {
+			oldest = s
+		}
+	}
+	if oldest != nil {
+		delete(m.sessions, oldest.ID)
+	}
+}
+
+// Delete forgets a session
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// Prune forgets sessions last updated before cutoff
+func (m *MemoryStore) Prune(cutoff time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.sessions {
+		if s.UpdatedAt.Before(cutoff) {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
+// FileStore keeps each session as a JSON file in a directory, so sessions
+// survive restarts
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates a FileStore in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns the file a session is kept in. IDs are checked first so one
+// can't name a file outside the directory.
+func (f *FileStore) path(id string) (string, error) {
+	if !validSessionID(id) {
+		return "", ErrSessionNotFound
+	}
+	return filepath.Join(f.dir, id+".json"), nil
+}
+
+// Get reads a session from its file
+func (f *FileStore) Get(id string) (*models.Session, error) {
+	path, err := f.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	session := &models.Session{}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", id, err)
+	}
+	return session, nil
+}
+
+// Save writes a session to its file. It's written beside the file and
+// renamed over it, so a crash never leaves half a session.
+func (f *FileStore) Save(session *models.Session) error {
+	path, err := f.path(session.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a session's file
+func (f *FileStore) Delete(id string) error {
+	path, err := f.path(id)
+	if err != nil {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// Prune removes the files of sessions last updated before cutoff. A file is
+// written whenever its session is updated, so only files older than cutoff
+// need reading.
+func (f *FileStore) Prune(cutoff time.Time) error {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || !validSessionID(id) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		session, err := f.Get(id)
+		if err != nil {
+			continue
+		}
+		if session.UpdatedAt.Before(cutoff) {
+			if err := f.Delete(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
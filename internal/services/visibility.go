@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"dconn.dev/internal/models"
+)
+
+// Line of sight, matching FogOfWar in game.js so the server and the client
+// agree on what the player has seen
+const (
+	visionRadius = 15  // Furthest a ray reaches, in half-tile steps
+	visionRays   = 360 // Rays cast around the player
+)
+
+// IsOpaque reports whether a world tile blocks line of sight. Tiles outside
+// every chunk are open water and don't; tiles of a chunk that can't be read
+// do, so nothing is revealed beyond them.
+func (ws *WorldService) IsOpaque(worldX, worldY int) bool {
+	chunkX, localX := splitCoord(worldX, ws.world.ChunkSize)
+	chunkY, localY := splitCoord(worldY, ws.world.ChunkSize)
+	if !ws.ChunkExists(chunkX, chunkY) {
+		return false
+	}
+
+	chunk, err := ws.loadChunk(chunkX, chunkY)
+	if err != nil {
+		return true
+	}
+	if localY >= len(chunk.Tiles) || localX >= len(chunk.Tiles[localY]) {
+		return true
+	}
+	return ws.world.TileDefinitions[chunk.Tiles[localY][localX]].Opaque
+}
+
+// VisibleFrom returns the world tiles a player standing at pos can see,
+// casting rays until they reach an opaque tile, which is itself seen
+func (ws *WorldService) VisibleFrom(pos models.Position) []models.Position {
+	seen := map[models.Position]bool{pos: true}
+	visible := []models.Position{pos}
+
+	for i := 0; i < visionRays; i++ {
+		angle := float64(i) / visionRays * 2 * math.Pi
+		dx, dy := math.Cos(angle), math.Sin(angle)
+
+		x, y := float64(pos.X)+0.5, float64(pos.Y)+0.5
+		for dist := 0; dist <= visionRadius; dist++ {
+			tile := models.Position{X: int(math.Floor(x)), Y: int(math.Floor(y))}
+			if !seen[tile] {
+				seen[tile] = true
+				visible = append(visible, tile)
+			}
+			if ws.IsOpaque(tile.X, tile.Y) {
+				break
+			}
+			x += dx * 0.5
+			y += dy * 0.5
+		}
+	}
+	return visible
+}
+
+// reveal marks tiles as explored in a session's fog masks, one per chunk:
+// a row of '.' and '#' for each tile row, '#' where the tile has been seen.
+// Tiles outside every chunk aren't recorded.
+func (ws *WorldService) reveal(session *models.Session, tiles []models.Position) {
+	size := ws.world.ChunkSize
+	rows := make(map[string][][]byte)
+	for _, t := range tiles {
+		chunkX, localX := splitCoord(t.X, size)
+		chunkY, localY := splitCoord(t.Y, size)
+		if !ws.ChunkExists(chunkX, chunkY) {
+			continue
+		}
+
+		key := fmt.Sprintf("%d,%d", chunkX, chunkY)
+		mask, ok := rows[key]
+		if !ok {
+			mask = decodeMask(session.Fog[key], size)
+			rows[key] = mask
+		}
+		mask[localY][localX] = '#'
+	}
+
+	if session.Fog == nil && len(rows) > 0 {
+		session.Fog = make(map[string][]string)
+	}
+	for key, mask := range rows {
+		encoded := make([]string, len(mask))
+		for i, row := range mask {
+			encoded[i] = string(row)
+		}
+		session.Fog[key] = encoded
+	}
+}
+
+// decodeMask returns a fog mask as editable rows, size by size, filling in
+// anything missing as unexplored
+func decodeMask(mask []string, size int) [][]byte {
+	rows := make([][]byte, size)
+	for y := range rows {
+		rows[y] = make([]byte, size)
+		for x := range rows[y] {
+			rows[y][x] = '.'
+		}
+		if y < len(mask) {
+			copy(rows[y], mask[y])
+		}
+	}
+	return rows
+}
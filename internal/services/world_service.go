@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"dconn.dev/internal/models"
 )
@@ -13,14 +14,20 @@ import (
 type WorldService struct {
 	world    *models.World
 	dataPath string
-	chunks   map[string]*models.Chunk // cached chunks
+
+	mu        sync.Mutex
+	chunks    map[string]*models.Chunk             // cached chunks
+	zones     *ZoneIndex                           // zones of the cached chunks
+	locations map[string][]*models.ProjectLocation // project ID to where the cached chunks place it
 }
 
 // NewWorldService creates a new WorldService
 func NewWorldService(dataPath string) (*WorldService, error) {
 	ws := &WorldService{
-		dataPath: dataPath,
-		chunks:   make(map[string]*models.Chunk),
+		dataPath:  dataPath,
+		chunks:    make(map[string]*models.Chunk),
+		zones:     NewZoneIndex(),
+		locations: make(map[string][]*models.ProjectLocation),
 	}
 
 	if err := ws.loadWorld(); err != nil {
@@ -62,8 +69,92 @@ func (ws *WorldService) GetWorldResponse() *models.WorldResponse {
 	}
 }
 
+// SpawnPoint returns the world tile new players start on
+func (ws *WorldService) SpawnPoint() models.Position {
+	return models.Position{
+		X: ws.world.SpawnChunk[0]*ws.world.ChunkSize + ws.world.SpawnLocal[0],
+		Y: ws.world.SpawnChunk[1]*ws.world.ChunkSize + ws.world.SpawnLocal[1],
+	}
+}
+
 // GetChunk returns a chunk by grid coordinates
 func (ws *WorldService) GetChunk(x, y int) (*models.ChunkResponse, error) {
+	chunk, err := ws.loadChunk(x, y)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ChunkResponse{
+		X:      x,
+		Y:      y,
+		Tiles:  chunk.Tiles,
+		Zones:  chunk.Zones,
+		Layers: chunk.Layers,
+	}, nil
+}
+
+// IsWalkable checks if a world tile can be walked on, using the chunk's
+// collision layer when it has one and the tile definitions otherwise
+func (ws *WorldService) IsWalkable(worldX, worldY int) bool {
+	chunkX, localX := splitCoord(worldX, ws.world.ChunkSize)
+	chunkY, localY := splitCoord(worldY, ws.world.ChunkSize)
+
+	chunk, err := ws.loadChunk(chunkX, chunkY)
+	if err != nil {
+		return false
+	}
+	if localY >= len(chunk.Tiles) || localX >= len(chunk.Tiles[localY]) {
+		return false
+	}
+
+	if blocked, ok := chunk.Layers.Blocked(localX, localY); ok {
+		return !blocked
+	}
+
+	if tile, exists := ws.world.TileDefinitions[chunk.Tiles[localY][localX]]; exists {
+		return tile.Walkable
+	}
+	return true
+}
+
+// splitCoord converts a world coordinate to a chunk index and an offset
+// within that chunk, flooring so negative coordinates land in negative chunks
+func splitCoord(world, chunkSize int) (chunk, local int) {
+	chunk = world / chunkSize
+	local = world % chunkSize
+	if local < 0 {
+		chunk--
+		local += chunkSize
+	}
+	return chunk, local
+}
+
+// GetInterior returns the interior of a structure in a chunk
+func (ws *WorldService) GetInterior(x, y int, id string) (*models.InteriorResponse, error) {
+	chunk, err := ws.loadChunk(x, y)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, interior := range chunk.Interiors {
+		if interior.ID == id {
+			return &models.InteriorResponse{
+				ChunkX: x,
+				ChunkY: y,
+				ID:     interior.ID,
+				Name:   interior.Name,
+				Tiles:  interior.Tiles,
+				Zones:  interior.Zones,
+				Spawn:  interior.Spawn,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("interior %s not found in chunk %d,%d", id, x, y)
+}
+
+// loadChunk returns a chunk from the cache, reading it from disk on first use
+func (ws *WorldService) loadChunk(x, y int) (*models.Chunk, error) {
 	key := fmt.Sprintf("%d,%d", x, y)
 
 	// Check if chunk exists in manifest
@@ -72,14 +163,12 @@ func (ws *WorldService) GetChunk(x, y int) (*models.ChunkResponse, error) {
 		return nil, fmt.Errorf("chunk %s not found", key)
 	}
 
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
 	// Check cache
 	if chunk, cached := ws.chunks[key]; cached {
-		return &models.ChunkResponse{
-			X:     x,
-			Y:     y,
-			Tiles: chunk.Tiles,
-			Zones: chunk.Zones,
-		}, nil
+		return chunk, nil
 	}
 
 	// Load from file
@@ -94,15 +183,61 @@ func (ws *WorldService) GetChunk(x, y int) (*models.ChunkResponse, error) {
 		return nil, fmt.Errorf("failed to parse chunk file: %w", err)
 	}
 
-	// Cache it
+	// Cache it, and index its zones and projects
 	ws.chunks[key] = chunk
+	ws.zones.Add(x, y, ws.world.ChunkSize, chunk.Zones)
+	ws.addLocations(x, y, chunk)
 
-	return &models.ChunkResponse{
-		X:     x,
-		Y:     y,
-		Tiles: chunk.Tiles,
-		Zones: chunk.Zones,
-	}, nil
+	return chunk, nil
+}
+
+// ZonesAt returns the zones at a world tile, the one that takes precedence
+// first
+func (ws *WorldService) ZonesAt(worldX, worldY int) []models.WorldZone {
+	chunkX, _ := splitCoord(worldX, ws.world.ChunkSize)
+	chunkY, _ := splitCoord(worldY, ws.world.ChunkSize)
+	if _, err := ws.loadChunk(chunkX, chunkY); err != nil {
+		return nil
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.zones.At(worldX, worldY)
+}
+
+// ZonesIn returns the zones overlapping a rectangle of world tiles
+func (ws *WorldService) ZonesIn(b models.Bounds) []models.WorldZone {
+	minX, _ := splitCoord(b.MinX, ws.world.ChunkSize)
+	minY, _ := splitCoord(b.MinY, ws.world.ChunkSize)
+	maxX, _ := splitCoord(b.MaxX, ws.world.ChunkSize)
+	maxY, _ := splitCoord(b.MaxY, ws.world.ChunkSize)
+
+	// Only chunks the manifest lists can have zones, so load those the
+	// rectangle touches rather than walking every coordinate inside it
+	for key := range ws.world.Chunks {
+		var x, y int
+		if _, err := fmt.Sscanf(key, "%d,%d", &x, &y); err != nil {
+			continue
+		}
+		if x >= minX && x <= maxX && y >= minY && y <= maxY {
+			ws.loadChunk(x, y)
+		}
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.zones.In(b)
+}
+
+// NearestZone returns the zone nearest a world tile that filter accepts
+// (nil accepts any) and how many steps away it is, or ok false if there is
+// none. It searches the whole world, so every chunk is loaded.
+func (ws *WorldService) NearestZone(worldX, worldY int, filter func(*models.WorldZone) bool) (zone models.WorldZone, distance int, ok bool) {
+	ws.loadAll()
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.zones.Nearest(worldX, worldY, filter)
 }
 
 // ChunkExists checks if a chunk exists at the given coordinates
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dconn.dev/internal/models"
+)
+
+// fixtureTiles is chunk 0,0 of the fixture world: a tower with one door,
+// facing south
+var fixtureTiles = []string{
+	"..........",
+	"..........",
+	"......###.",
+	"......#.#.",
+	"......#+#.",
+	"..........",
+	"..........",
+	"..........",
+	"..........",
+	"..........",
+}
+
+// writeWorld writes a world of 10-tile chunks to a temporary directory and
+// opens it. Chunks are keyed "x,y"; spawn is tile 1,4 of chunk 0,0.
+func writeWorld(t *testing.T, chunks map[string]models.Chunk) *WorldService {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "chunks"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	world := models.World{
+		ChunkSize:  10,
+		SpawnLocal: [2]int{1, 4},
+		TileDefinitions: map[string]models.Tile{
+			".": {Character: ".", Walkable: true},
+			"#": {Character: "#", Opaque: true},
+			"+": {Character: "+", Walkable: true},
+		},
+		Chunks: make(map[string]models.ChunkRef),
+	}
+	for key, chunk := range chunks {
+		file := "chunks/" + strings.ReplaceAll(key, ",", "_") + ".json"
+		world.Chunks[key] = models.ChunkRef{Name: key, File: file}
+		writeJSON(t, filepath.Join(dir, file), chunk)
+	}
+	writeJSON(t, filepath.Join(dir, "world.json"), world)
+
+	ws, err := NewWorldService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+func writeJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// gridTiles splits rows of glyphs into a chunk's tile grid
+func gridTiles(rows []string) [][]string {
+	tiles := make([][]string, len(rows))
+	for i, row := range rows {
+		tiles[i] = strings.Split(row, "")
+	}
+	return tiles
+}
+
+// fixtureWorld has the tower in chunk 0,0 and a project placed twice, in
+// chunks 0,0 and -1,0
+func fixtureWorld(t *testing.T) *WorldService {
+	open := make([]string, 10)
+	for i := range open {
+		open[i] = strings.Repeat(".", 10)
+	}
+	return writeWorld(t, map[string]models.Chunk{
+		"0,0": {
+			Tiles: gridTiles(fixtureTiles),
+			Zones: []models.Zone{
+				{Name: "Tower", Type: models.ZoneProject, ProjectID: "tower", Bounds: models.Bounds{MinX: 6, MinY: 2, MaxX: 8, MaxY: 4}},
+				{Name: "Tower Exhibit", Type: models.ZoneProject, ProjectID: "tower", Bounds: models.Bounds{MinX: 7, MinY: 3, MaxX: 7, MaxY: 3}},
+				{Name: "Tower Door", Type: models.ZoneInterior, ProjectID: "tower", Bounds: models.Bounds{MinX: 7, MinY: 4, MaxX: 7, MaxY: 4}},
+				{Name: "East Hut", Type: models.ZoneProject, ProjectID: "twice", Bounds: models.Bounds{MinX: 0, MinY: 8, MaxX: 1, MaxY: 9}},
+			},
+		},
+		"-1,0": {
+			Tiles: gridTiles(open),
+			Zones: []models.Zone{
+				{Name: "West Hut", Type: models.ZoneProject, ProjectID: "twice", Bounds: models.Bounds{MinX: 2, MinY: 2, MaxX: 3, MaxY: 3}},
+				{Name: "Well", Type: models.ZoneLandmark, Bounds: models.Bounds{MinX: 5, MinY: 5, MaxX: 5, MaxY: 5}},
+			},
+		},
+	})
+}
+
+func TestProjectLocation(t *testing.T) {
+	ws := fixtureWorld(t)
+
+	want := &models.ProjectLocation{
+		Name:   "Tower",
+		Bounds: models.Bounds{MinX: 6, MinY: 2, MaxX: 8, MaxY: 4},
+		Doors:  []models.Position{{X: 7, Y: 4}},
+	}
+	if got := ws.ProjectLocation("tower"); !reflect.DeepEqual(got, want) {
+		t.Errorf("tower at %+v, want %+v", got, want)
+	}
+
+	// Placed twice: the chunk further west on the same row wins, its
+	// bounds in world tiles
+	want = &models.ProjectLocation{
+		ChunkX: -1,
+		Name:   "West Hut",
+		Bounds: models.Bounds{MinX: -8, MinY: 2, MaxX: -7, MaxY: 3},
+	}
+	if got := ws.ProjectLocation("twice"); !reflect.DeepEqual(got, want) {
+		t.Errorf("twice at %+v, want %+v", got, want)
+	}
+
+	if got := ws.ProjectLocation("missing"); got != nil {
+		t.Errorf("unplaced project at %+v", got)
+	}
+
+	wantPlacements := []models.Placement{
+		{ProjectID: "tower", Name: "Tower"},
+		{ProjectID: "twice", Name: "East Hut"},
+		{ProjectID: "twice", Name: "West Hut", ChunkX: -1},
+	}
+	got := ws.Placements()
+	if len(got) != len(wantPlacements) {
+		t.Fatalf("placements %+v, want %+v", got, wantPlacements)
+	}
+	for _, p := range wantPlacements {
+		found := false
+		for _, g := range got {
+			found = found || g == p
+		}
+		if !found {
+			t.Errorf("placements %+v missing %+v", got, p)
+		}
+	}
+}
+
+func TestPathToProject(t *testing.T) {
+	ws := fixtureWorld(t)
+
+	// Round the tower's west wall to its door
+	path, err := ws.PathToProject(models.Position{X: 1, Y: 4}, "tower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(path) != 9 || path[0] != (models.Position{X: 1, Y: 4}) || path[len(path)-1] != (models.Position{X: 7, Y: 4}) {
+		t.Errorf("path %v, want 8 steps from 1,4 to the door at 7,4", path)
+	}
+	for i, p := range path {
+		if !ws.IsWalkable(p.X, p.Y) {
+			t.Errorf("step %d at %v is not walkable", i, p)
+		}
+		if i > 0 && abs(p.X-path[i-1].X)+abs(p.Y-path[i-1].Y) != 1 {
+			t.Errorf("step %d jumps from %v to %v", i, path[i-1], p)
+		}
+	}
+
+	// Without doors, the route ends on the structure; across a chunk edge
+	path, err = ws.PathToProject(models.Position{X: 4, Y: 9}, "twice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end := path[len(path)-1]; end.X < -8 || end.X > -7 || end.Y < 2 || end.Y > 3 {
+		t.Errorf("path to the west hut ends at %v, outside it", end)
+	}
+
+	if _, err := ws.PathToProject(models.Position{X: 6, Y: 2}, "tower"); err == nil {
+		t.Error("path from inside a wall")
+	}
+	if _, err := ws.PathToProject(models.Position{X: 1, Y: 4}, "missing"); err == nil {
+		t.Error("path to an unplaced project")
+	}
+}
+
+// The fixture world, checked against catalogues it disagrees with, shows
+// each fault the server warns about at startup
+func TestFixtureConsistency(t *testing.T) {
+	ws := fixtureWorld(t)
+
+	report := models.CheckConsistency([]models.Project{{ID: "tower"}, {ID: "twice"}}, ws.Placements())
+	if len(report.Duplicates) != 1 || len(report.Duplicates[0]) != 2 || len(report.Orphans)+len(report.Unplaced) != 0 {
+		t.Errorf("with every project catalogued: %q, want one duplicate", report.Problems())
+	}
+
+	report = models.CheckConsistency([]models.Project{{ID: "tower"}, {ID: "lost", Featured: true}}, ws.Placements())
+	if len(report.Orphans) != 2 || len(report.Unplaced) != 1 || len(report.Duplicates) != 0 {
+		t.Errorf("with twice missing and lost unplaced: %q, want two orphans and one unplaced", report.Problems())
+	}
+}
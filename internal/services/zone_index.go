@@ -0,0 +1,257 @@
+package services
+
+import (
+	"sort"
+
+	"dconn.dev/internal/models"
+)
+
+// zoneBucketSize is the side, in world tiles, of the grid cells the zone
+// index buckets zones into
+const zoneBucketSize = 8
+
+// bucket identifies a cell of the zone index grid
+type bucket struct {
+	x, y int
+}
+
+// indexedZone is a zone in the index, with its position in its chunk's zone
+// list so results come out in the same order whichever chunk loaded first
+type indexedZone struct {
+	models.WorldZone
+	seq int
+}
+
+// before orders zones by chunk, then by their order within the chunk
+func (z *indexedZone) before(other *indexedZone) bool {
+	if z.ChunkY != other.ChunkY {
+		return z.ChunkY < other.ChunkY
+	}
+	if z.ChunkX != other.ChunkX {
+		return z.ChunkX < other.ChunkX
+	}
+	return z.seq < other.seq
+}
+
+// ZoneIndex finds zones by world position. Each zone is filed under every
+// grid cell its bounds touch, so lookups only check the zones nearby.
+type ZoneIndex struct {
+	zones    []*indexedZone
+	buckets  map[bucket][]*indexedZone
+	min, max bucket // Extent of the occupied cells
+}
+
+// NewZoneIndex creates an empty ZoneIndex
+func NewZoneIndex() *ZoneIndex {
+	return &ZoneIndex{buckets: make(map[bucket][]*indexedZone)}
+}
+
+// Add indexes a chunk's zones. Their bounds are local to the chunk, whose
+// top-left tile is at (chunkX*chunkSize, chunkY*chunkSize) in the world.
+func (idx *ZoneIndex) Add(chunkX, chunkY, chunkSize int, zones []models.Zone) {
+	originX, originY := chunkX*chunkSize, chunkY*chunkSize
+	for i, zone := range zones {
+		z := &indexedZone{
+			WorldZone: models.WorldZone{
+				Zone:   zone,
+				ChunkX: chunkX,
+				ChunkY: chunkY,
+				WorldBounds: models.Bounds{
+					MinX: zone.Bounds.MinX + originX,
+					MaxX: zone.Bounds.MaxX + originX,
+					MinY: zone.Bounds.MinY + originY,
+					MaxY: zone.Bounds.MaxY + originY,
+				},
+			},
+			seq: i,
+		}
+
+		lo := bucketOf(z.WorldBounds.MinX, z.WorldBounds.MinY)
+		hi := bucketOf(z.WorldBounds.MaxX, z.WorldBounds.MaxY)
+		if len(idx.zones) == 0 {
+			idx.min, idx.max = lo, hi
+		}
+		idx.min = bucket{min(idx.min.x, lo.x), min(idx.min.y, lo.y)}
+		idx.max = bucket{max(idx.max.x, hi.x), max(idx.max.y, hi.y)}
+
+		idx.zones = append(idx.zones, z)
+		for by := lo.y; by <= hi.y; by++ {
+			for bx := lo.x; bx <= hi.x; bx++ {
+				b := bucket{bx, by}
+				idx.buckets[b] = append(idx.buckets[b], z)
+			}
+		}
+	}
+}
+
+// At returns the zones containing the world tile (x, y), the one that takes
+// precedence first
+func (idx *ZoneIndex) At(x, y int) []models.WorldZone {
+	var found []*indexedZone
+	for _, z := range idx.buckets[bucketOf(x, y)] {
+		if z.contains(x, y) {
+			found = append(found, z)
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.Outranks(&b.Zone) != b.Outranks(&a.Zone) {
+			return a.Outranks(&b.Zone)
+		}
+		return a.before(b)
+	})
+	return worldZones(found)
+}
+
+// In returns the zones whose bounds overlap the world rectangle b
+func (idx *ZoneIndex) In(b models.Bounds) []models.WorldZone {
+	lo, hi := bucketOf(b.MinX, b.MinY), bucketOf(b.MaxX, b.MaxY)
+	lo = bucket{max(lo.x, idx.min.x), max(lo.y, idx.min.y)}
+	hi = bucket{min(hi.x, idx.max.x), min(hi.y, idx.max.y)}
+
+	seen := make(map[*indexedZone]bool)
+	var found []*indexedZone
+	for by := lo.y; by <= hi.y; by++ {
+		for bx := lo.x; bx <= hi.x; bx++ {
+			for _, z := range idx.buckets[bucket{bx, by}] {
+				w := z.WorldBounds
+				if seen[z] || w.MaxX < b.MinX || w.MinX > b.MaxX || w.MaxY < b.MinY || w.MinY > b.MaxY {
+					continue
+				}
+				seen[z] = true
+				found = append(found, z)
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].before(found[j]) })
+	return worldZones(found)
+}
+
+// Nearest returns the zone closest to the world tile (x, y) that filter
+// accepts (nil accepts any) and its distance in steps, or ok false if there
+// is none. Distance is to the zone's nearest tile, 0 when (x, y) is in it.
+func (idx *ZoneIndex) Nearest(x, y int, filter func(*models.WorldZone) bool) (zone models.WorldZone, distance int, ok bool) {
+	if len(idx.zones) == 0 {
+		return zone, 0, false
+	}
+
+	// Search rings of cells outward from (x, y), or from the nearest
+	// occupied cell when it's outside them all. Every tile in ring r is
+	// more than (r-1)*zoneBucketSize steps away, so once something that
+	// close has been found no further ring can beat it. Starting from the
+	// nearest cell keeps that true: along any axis it was moved, every zone
+	// lies beyond it from (x, y).
+	center := bucketOf(x, y)
+	center = bucket{
+		min(max(center.x, idx.min.x), idx.max.x),
+		min(max(center.y, idx.min.y), idx.max.y),
+	}
+	last := max(
+		abs(center.x-idx.min.x), abs(center.x-idx.max.x),
+		abs(center.y-idx.min.y), abs(center.y-idx.max.y),
+	)
+
+	var best *indexedZone
+	seen := make(map[*indexedZone]bool)
+	for r := 0; r <= last; r++ {
+		if best != nil && distance <= (r-1)*zoneBucketSize {
+			break
+		}
+		for _, b := range ring(center, r) {
+			for _, z := range idx.buckets[b] {
+				if seen[z] {
+					continue
+				}
+				seen[z] = true
+				if filter != nil && !filter(&z.WorldZone) {
+					continue
+				}
+				d := z.distance(x, y)
+				if d < 0 {
+					continue // A mask with no tiles in it
+				}
+				if best == nil || d < distance || (d == distance && z.before(best)) {
+					best, distance = z, d
+				}
+			}
+		}
+	}
+
+	if best == nil {
+		return zone, 0, false
+	}
+	return best.WorldZone, distance, true
+}
+
+// contains reports whether the world tile (x, y) is in the zone
+func (z *indexedZone) contains(x, y int) bool {
+	return z.Contains(x-(z.WorldBounds.MinX-z.Bounds.MinX), y-(z.WorldBounds.MinY-z.Bounds.MinY))
+}
+
+// distance is the number of steps from the world tile (x, y) to the
+// nearest tile of the zone, or -1 if its mask leaves it no tiles
+func (z *indexedZone) distance(x, y int) int {
+	w := z.WorldBounds
+	if z.Mask == nil {
+		return max(w.MinX-x, 0, x-w.MaxX) + max(w.MinY-y, 0, y-w.MaxY)
+	}
+
+	best := -1
+	for row, line := range z.Mask {
+		for col := range line {
+			if line[col] != '#' {
+				continue
+			}
+			if d := abs(w.MinX+col-x) + abs(w.MinY+row-y); best < 0 || d < best {
+				best = d
+			}
+		}
+	}
+	return best
+}
+
+// bucketOf returns the cell containing the world tile (x, y), flooring so
+// negative coordinates land in negative cells
+func bucketOf(x, y int) bucket {
+	bx, _ := splitCoord(x, zoneBucketSize)
+	by, _ := splitCoord(y, zoneBucketSize)
+	return bucket{bx, by}
+}
+
+// ring returns the cells exactly r cells from center, counting diagonals
+// as one
+func ring(center bucket, r int) []bucket {
+	if r == 0 {
+		return []bucket{center}
+	}
+	cells := make([]bucket, 0, 8*r)
+	for d := -r; d <= r; d++ {
+		cells = append(cells,
+			bucket{center.x + d, center.y - r},
+			bucket{center.x + d, center.y + r})
+	}
+	for d := -r + 1; d < r; d++ {
+		cells = append(cells,
+			bucket{center.x - r, center.y + d},
+			bucket{center.x + r, center.y + d})
+	}
+	return cells
+}
+
+// worldZones copies indexed zones out for callers
+func worldZones(zones []*indexedZone) []models.WorldZone {
+	out := make([]models.WorldZone, len(zones))
+	for i, z := range zones {
+		out[i] = z.WorldZone
+	}
+	return out
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
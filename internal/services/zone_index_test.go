@@ -0,0 +1,189 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"dconn.dev/internal/models"
+)
+
+// zoneAt makes a zone covering the rectangle from (minX, minY) to (maxX,
+// maxY) in its chunk
+func zoneAt(name string, minX, minY, maxX, maxY int) models.Zone {
+	return models.Zone{Name: name, Bounds: models.Bounds{MinX: minX, MinY: minY, MaxX: maxX, MaxY: maxY}}
+}
+
+// nearestByScan is Nearest the slow way: every zone checked, ties going to
+// the zone first in chunk order
+func nearestByScan(idx *ZoneIndex, x, y int) (name string, distance int) {
+	var best *indexedZone
+	for _, z := range idx.zones {
+		d := z.distance(x, y)
+		if d >= 0 && (best == nil || d < distance || (d == distance && z.before(best))) {
+			best, distance = z, d
+		}
+	}
+	if best == nil {
+		return "", 0
+	}
+	return best.Name, distance
+}
+
+// randomIndex fills a few 50-tile chunks either side of the origin with
+// small zones
+func randomIndex(rng *rand.Rand) *ZoneIndex {
+	idx := NewZoneIndex()
+	for cy := -2; cy <= 1; cy++ {
+		for cx := -1; cx <= 2; cx++ {
+			var zones []models.Zone
+			for i := rng.Intn(4); i > 0; i-- {
+				x, y := rng.Intn(45), rng.Intn(45)
+				zones = append(zones, zoneAt(fmt.Sprintf("%d,%d#%d", cx, cy, i), x, y, x+rng.Intn(5), y+rng.Intn(5)))
+			}
+			idx.Add(cx, cy, 50, zones)
+		}
+	}
+	return idx
+}
+
+// Nearest agrees with checking every zone, from inside the index and from
+// far outside it, where the search starts at the nearest occupied cell
+func TestNearestMatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 20; trial++ {
+		idx := randomIndex(rng)
+		points := []models.Position{
+			{X: 1 << 30, Y: 1 << 30}, {X: -1 << 30, Y: 7}, {X: 3, Y: -1 << 30},
+			{X: 10000, Y: -20}, {X: -75, Y: 10000},
+		}
+		for i := 0; i < 200; i++ {
+			points = append(points, models.Position{X: rng.Intn(400) - 200, Y: rng.Intn(400) - 200})
+		}
+
+		for _, p := range points {
+			wantName, wantDist := nearestByScan(idx, p.X, p.Y)
+			zone, dist, ok := idx.Nearest(p.X, p.Y, nil)
+			if ok != (wantName != "") || zone.Name != wantName || dist != wantDist {
+				t.Fatalf("trial %d: Nearest(%d, %d) = %q at %d (ok %v), want %q at %d",
+					trial, p.X, p.Y, zone.Name, dist, ok, wantName, wantDist)
+			}
+		}
+	}
+}
+
+// A masked zone counts only its '#' tiles, and one with none is never
+// nearest
+func TestNearestMasks(t *testing.T) {
+	empty := zoneAt("empty", 0, 0, 2, 2)
+	empty.Mask = []string{"...", "...", "..."}
+	ring := zoneAt("ring", 10, 0, 12, 2)
+	ring.Mask = []string{"#..", "...", "..."}
+
+	idx := NewZoneIndex()
+	idx.Add(0, 0, 50, []models.Zone{empty})
+	if _, _, ok := idx.Nearest(1, 1, nil); ok {
+		t.Error("found a zone with no tiles")
+	}
+
+	idx.Add(1, 0, 50, []models.Zone{ring, zoneAt("far", 0, 30, 0, 30)})
+	// ring's one tile is (60, 0), 60 away; far is at (50, 30), 78 away
+	zone, dist, ok := idx.Nearest(1, 1, nil)
+	if !ok || zone.Name != "ring" || dist != 60 {
+		t.Errorf("Nearest(1, 1) = %q at %d, want ring at 60, skipping the empty mask", zone.Name, dist)
+	}
+
+	// Inside a masked zone's bounds but off its tiles isn't distance 0
+	if zone, dist, _ := idx.Nearest(61, 1, nil); zone.Name != "ring" || dist != 2 {
+		t.Errorf("Nearest(61, 1) = %q at %d, want ring at 2", zone.Name, dist)
+	}
+}
+
+// Zones equally near go by chunk row, then chunk column, then their order
+// in the chunk, whichever order chunks were added in
+func TestNearestTieBreak(t *testing.T) {
+	// Four zones 5 steps from (50, 50), in four chunks, plus a second zone
+	// in the first chunk listed after one at the same distance
+	idx := NewZoneIndex()
+	idx.Add(1, 1, 50, []models.Zone{zoneAt("below right", 5, 0, 5, 0)})
+	idx.Add(0, 1, 50, []models.Zone{zoneAt("below left", 45, 0, 45, 0)})
+	idx.Add(1, 0, 50, []models.Zone{zoneAt("above right", 0, 45, 0, 45)})
+	idx.Add(0, 0, 50, []models.Zone{zoneAt("above left", 46, 49, 46, 49), zoneAt("above left too", 49, 46, 49, 46)})
+
+	zone, dist, ok := idx.Nearest(50, 50, nil)
+	if !ok || zone.Name != "above left" || dist != 5 {
+		t.Errorf("Nearest(50, 50) = %q at %d, want above left at 5", zone.Name, dist)
+	}
+
+	// The filter decides before the tie-break does
+	right := func(z *models.WorldZone) bool { return z.ChunkX == 1 }
+	if zone, _, _ := idx.Nearest(50, 50, right); zone.Name != "above right" {
+		t.Errorf("Nearest(50, 50, right) = %q, want above right", zone.Name)
+	}
+	below := func(z *models.WorldZone) bool { return z.ChunkY == 1 }
+	if zone, _, _ := idx.Nearest(50, 50, below); zone.Name != "below left" {
+		t.Errorf("Nearest(50, 50, below) = %q, want below left", zone.Name)
+	}
+}
+
+// In finds the same zones as checking every one, for rectangles inside,
+// across the edge of and wholly outside the index
+func TestInMatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	idx := randomIndex(rng)
+
+	rects := []models.Bounds{
+		{MinX: -1 << 30, MinY: -1 << 30, MaxX: 1 << 30, MaxY: 1 << 30},
+		{MinX: -1 << 30, MinY: 0, MaxX: 10, MaxY: 20},
+		{MinX: 90, MinY: -120, MaxX: 1 << 30, MaxY: -80},
+		{MinX: 5000, MinY: 5000, MaxX: 6000, MaxY: 6000},
+		{MinX: -6000, MinY: -6000, MaxX: -5000, MaxY: -5000},
+	}
+	for i := 0; i < 200; i++ {
+		x, y := rng.Intn(400)-200, rng.Intn(400)-200
+		rects = append(rects, models.Bounds{MinX: x, MinY: y, MaxX: x + rng.Intn(80), MaxY: y + rng.Intn(80)})
+	}
+
+	for _, b := range rects {
+		var want []string
+		for _, z := range idx.zones {
+			w := z.WorldBounds
+			if w.MaxX >= b.MinX && w.MinX <= b.MaxX && w.MaxY >= b.MinY && w.MinY <= b.MaxY {
+				want = append(want, z.Name)
+			}
+		}
+		var got []string
+		for _, z := range idx.In(b) {
+			got = append(got, z.Name)
+		}
+
+		// Both lists are in chunk order, so they compare directly
+		if fmt.Sprint(got) != fmt.Sprint(sortedNames(idx, want)) {
+			t.Errorf("In(%+v) = %v, want %v", b, got, want)
+		}
+	}
+}
+
+// sortedNames puts zone names in the index's chunk order
+func sortedNames(idx *ZoneIndex, names []string) []string {
+	in := make(map[string]bool)
+	for _, n := range names {
+		in[n] = true
+	}
+	var ordered []*indexedZone
+	for _, z := range idx.zones {
+		if in[z.Name] {
+			ordered = append(ordered, z)
+		}
+	}
+	for i := 1; i < len(ordered); i++ {
+		for j := i; j > 0 && ordered[j].before(ordered[j-1]); j-- {
+			ordered[j], ordered[j-1] = ordered[j-1], ordered[j]
+		}
+	}
+	out := make([]string, len(ordered))
+	for i, z := range ordered {
+		out[i] = z.Name
+	}
+	return out
+}
@@ -24,6 +24,15 @@ export class API {
         return await response.json();
     }
 
+    // Interior of a structure inside a chunk
+    async getInterior(chunkX, chunkY, id) {
+        const response = await fetch(`${this.baseURL}/chunks/${chunkX}/${chunkY}/interiors/${encodeURIComponent(id)}`);
+        if (!response.ok) {
+            throw new Error(`Failed to fetch interior ${id}`);
+        }
+        return await response.json();
+    }
+
     // Legacy: full map (kept for compatibility)
     async getFullMap() {
         const response = await fetch(`${this.baseURL}/game/map`);
@@ -33,14 +42,32 @@ export class API {
         return await response.json();
     }
 
-    async getProjects() {
-        const response = await fetch(`${this.baseURL}/projects`);
+    // Projects, optionally narrowed by { q, tech, year, featured, sort,
+    // page, per_page }
+    async getProjects(params = {}) {
+        const query = new URLSearchParams();
+        for (const [key, value] of Object.entries(params)) {
+            if (value !== undefined && value !== null && value !== '') {
+                query.set(key, Array.isArray(value) ? value.join(',') : value);
+            }
+        }
+        const search = query.toString();
+        const response = await fetch(`${this.baseURL}/projects${search ? '?' + search : ''}`);
         if (!response.ok) {
             throw new Error('Failed to fetch projects');
         }
         return await response.json();
     }
 
+    // Technologies across all projects, with how many use each
+    async getTech() {
+        const response = await fetch(`${this.baseURL}/tech`);
+        if (!response.ok) {
+            throw new Error('Failed to fetch technologies');
+        }
+        return await response.json();
+    }
+
     async getProject(id) {
         const response = await fetch(`${this.baseURL}/projects/${id}`);
         if (!response.ok) {
@@ -48,4 +75,45 @@ export class API {
         }
         return await response.json();
     }
+
+    // Resume the visitor's session (kept in a cookie) or start a new one
+    async startSession() {
+        const response = await fetch(`${this.baseURL}/session`, { method: 'POST' });
+        if (!response.ok) {
+            throw new Error('Failed to start session');
+        }
+        return await response.json();
+    }
+
+    // Record the player's overworld position. Returns the session, with
+    // rejected set when the server refused the position and the player
+    // should be put back where it says, or throttled set when it came
+    // sooner than the move delay allows and should be sent again later.
+    async savePosition(x, y) {
+        const response = await fetch(`${this.baseURL}/session/position`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ x, y })
+        });
+        if (response.status === 409) {
+            return { ...(await response.json()), rejected: true };
+        }
+        if (response.status === 429) {
+            return { ...(await response.json()), throttled: true };
+        }
+        if (!response.ok) {
+            throw new Error('Failed to save position');
+        }
+        return await response.json();
+    }
+
+    // What the session has explored: { chunk_size, chunks: { "x,y": mask } },
+    // each mask a string per tile row with '#' where a tile has been seen
+    async getFog() {
+        const response = await fetch(`${this.baseURL}/session/fog`);
+        if (!response.ok) {
+            throw new Error('Failed to fetch explored map');
+        }
+        return await response.json();
+    }
 }
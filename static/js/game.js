@@ -1,4 +1,48 @@
-import { API } from './api.js?v=7';
+import { API } from './api.js?v=12';
+
+// Tiles that block vision, for manifests that predate the opaque flag
+const OPAQUE_TILES = new Set([
+    '#',  // walls
+    'B',  // brick walls
+    'W',  // wood walls
+    'M',  // mountain peaks
+    'A',  // mountain base
+    'T',  // large trees
+]);
+
+// opaqueTest returns a function reporting whether a glyph blocks vision,
+// using the manifest's opaque flags when it has them
+function opaqueTest(tileDefinitions) {
+    const flagged = Object.values(tileDefinitions).some(def => 'opaque' in def);
+    if (!flagged) {
+        return char => OPAQUE_TILES.has(char);
+    }
+    return char => tileDefinitions[char]?.opaque === true;
+}
+
+// zoneContains checks a zone's bounds, then its mask if it has one
+function zoneContains(zone, x, y) {
+    const b = zone.bounds;
+    if (x < b.min_x || x > b.max_x || y < b.min_y || y > b.max_y) return false;
+    if (!zone.mask) return true;
+    return zone.mask[y - b.min_y]?.[x - b.min_x] === '#';
+}
+
+// zoneAt picks the zone at (x, y): highest priority first, then the
+// smallest, then the first listed
+function zoneAt(zones, x, y) {
+    const area = z => (z.bounds.max_x - z.bounds.min_x + 1) * (z.bounds.max_y - z.bounds.min_y + 1);
+    let best = null;
+    for (const zone of zones || []) {
+        if (!zoneContains(zone, x, y)) continue;
+        const priority = zone.priority || 0;
+        const bestPriority = best?.priority || 0;
+        if (!best || priority > bestPriority || (priority === bestPriority && area(zone) < area(best))) {
+            best = zone;
+        }
+    }
+    return best;
+}
 
 // FogOfWar handles visibility and exploration tracking
 class FogOfWar {
@@ -60,6 +104,21 @@ class FogOfWar {
         }
     }
 
+    // Mark tiles explored on an earlier visit, from the server's masks
+    restore(fog) {
+        const size = fog.chunk_size;
+        for (const [key, mask] of Object.entries(fog.chunks)) {
+            const [chunkX, chunkY] = key.split(',').map(Number);
+            mask.forEach((row, y) => {
+                for (let x = 0; x < row.length; x++) {
+                    if (row[x] === '#') {
+                        this.explored.add(`${chunkX * size + x},${chunkY * size + y}`);
+                    }
+                }
+            });
+        }
+    }
+
     // Get visibility state for rendering
     getVisibilityState(x, y) {
         if (this.isVisible(x, y)) {
@@ -79,6 +138,7 @@ class ChunkManager {
         this.chunks = new Map();     // Loaded chunks: "x,y" -> chunk data
         this.loading = new Set();    // Chunks currently being fetched
         this.chunkSize = 50;         // Will be set from world manifest
+        this.opaqueTile = null;      // Will be set from world manifest
 
         // Edge generation tiles
         this.waterTile = { char: '~', color: '#4da6ff' };
@@ -89,6 +149,7 @@ class ChunkManager {
     async init() {
         this.world = await this.api.getWorld();
         this.chunkSize = this.world.chunk_size;
+        this.opaqueTile = opaqueTest(this.world.tile_definitions);
 
         // Load spawn chunk immediately
         const [sx, sy] = this.world.spawn_chunk;
@@ -214,6 +275,10 @@ class ChunkManager {
 
         if (!char) return false;
 
+        // The collision layer, when present, overrides the tile definition
+        const collision = chunk.layers?.collision?.[localY]?.[localX];
+        if (collision) return collision !== '#';
+
         const tileDef = this.world.tile_definitions[char];
         return tileDef ? tileDef.walkable : true;
     }
@@ -225,14 +290,7 @@ class ChunkManager {
 
         if (!this.chunks.has(key)) return null;
 
-        const chunk = this.chunks.get(key);
-        for (const zone of chunk.zones || []) {
-            if (localX >= zone.bounds.min_x && localX <= zone.bounds.max_x &&
-                localY >= zone.bounds.min_y && localY <= zone.bounds.max_y) {
-                return zone;
-            }
-        }
-        return null;
+        return zoneAt(this.chunks.get(key).zones, localX, localY);
     }
 
     // Get current tile type name
@@ -274,18 +332,60 @@ class ChunkManager {
 
         if (!char) return true;
 
-        // Tiles that block vision
-        const opaqueTiles = new Set([
-            '#',  // walls
-            'B',  // brick walls
-            'W',  // wood walls
-            'M',  // mountain peaks
-            'A',  // mountain base
-            'T',  // large trees
-        ]);
+        return this.opaqueTile(char);
+    }
+}
+
+// InteriorMap serves tiles and zones for the inside of a structure, with the
+// same interface as ChunkManager so the game can swap between them
+class InteriorMap {
+    constructor(interior, tileDefinitions) {
+        this.interior = interior;
+        this.tileDefinitions = tileDefinitions;
+        this.opaqueTile = opaqueTest(tileDefinitions);
+        this.voidTile = { char: ' ', color: '#1a1a1a' };
+    }
+
+    charAt(x, y) {
+        return this.interior.tiles[y]?.[x];
+    }
+
+    getTile(x, y) {
+        const char = this.charAt(x, y);
+        if (!char) return this.voidTile;
+
+        const tileDef = this.tileDefinitions[char];
+        if (tileDef) {
+            return { char: tileDef.char, color: tileDef.color };
+        }
+
+        return { char, color: '#808080' };
+    }
+
+    isWalkable(x, y) {
+        const char = this.charAt(x, y);
+        if (!char) return false;
+
+        const tileDef = this.tileDefinitions[char];
+        return tileDef ? tileDef.walkable : true;
+    }
+
+    getZoneAt(x, y) {
+        return zoneAt(this.interior.zones, x, y);
+    }
+
+    getTileType(x, y) {
+        const char = this.charAt(x, y);
+        if (!char) return 'wall';
+        return this.tileDefinitions[char]?.type || 'unknown';
+    }
 
-        return opaqueTiles.has(char);
+    isOpaque(x, y) {
+        const char = this.charAt(x, y);
+        return !char || this.opaqueTile(char);
     }
+
+    prefetchAround() {}
 }
 
 // Main Game class
@@ -294,6 +394,12 @@ class Game {
         this.api = new API();
         this.chunkManager = new ChunkManager(this.api);
         this.fogOfWar = new FogOfWar();
+
+        // The map the player is currently on: the chunked overworld, or an
+        // InteriorMap while inside a structure
+        this.map = this.chunkManager;
+        this.overworld = null;  // Saved position and fog while inside
+        this.transitioning = false;
         this.viewport = document.getElementById('viewport');
         this.position = { x: 0, y: 0 };
         this.viewportWidth = 40;
@@ -304,6 +410,13 @@ class Game {
         this.moveInterval = null;
         this.moveDelay = 120;
 
+        // Session: overworld position is reported at most every
+        // reportDelay ms, one request at a time
+        this.session = null;
+        this.reportDelay = 500;
+        this.reportTimeout = null;
+        this.reports = Promise.resolve();
+
         // Fog of war colors
         this.hiddenColor = '#1a1a1a';
         this.exploredDim = 0.4;  // Brightness multiplier for explored tiles
@@ -313,9 +426,10 @@ class Game {
         try {
             // Initialize chunk manager and get spawn position
             this.position = await this.chunkManager.init();
+            await this.resumeSession();
 
             // Prefetch surrounding chunks
-            this.chunkManager.prefetchAround(this.position.x, this.position.y);
+            this.map.prefetchAround(this.position.x, this.position.y);
 
             this.calculateViewportSize();
             this.render();
@@ -327,6 +441,61 @@ class Game {
         }
     }
 
+    // Pick up where a returning visitor left off. The game still works
+    // without a session, it just won't remember the player.
+    async resumeSession() {
+        try {
+            this.session = await this.api.startSession();
+            if (this.session.resumed) {
+                this.position = { ...this.session.position };
+                this.fogOfWar.restore(await this.api.getFog());
+            }
+        } catch (error) {
+            console.error('Failed to start session:', error);
+            this.session = null;
+        }
+    }
+
+    // Tell the server where the player stands on the overworld: now if
+    // immediate, otherwise after reportDelay. Positions inside interiors
+    // aren't reported; the door is.
+    reportPosition(immediate = false) {
+        if (!this.session || this.map !== this.chunkManager) return;
+
+        if (immediate) {
+            clearTimeout(this.reportTimeout);
+            this.reportTimeout = null;
+            this.sendPosition({ ...this.position });
+        } else if (!this.reportTimeout) {
+            this.reportTimeout = setTimeout(() => {
+                this.reportTimeout = null;
+                if (this.map === this.chunkManager) {
+                    this.sendPosition({ ...this.position });
+                }
+            }, this.reportDelay);
+        }
+    }
+
+    // Queue a position report. If the server refuses it, put the player back
+    // on the last position it accepted; if it came too soon, try again later.
+    sendPosition(position) {
+        this.reports = this.reports.then(async () => {
+            try {
+                const session = await this.api.savePosition(position.x, position.y);
+                if (session.rejected && this.map === this.chunkManager) {
+                    this.position = { ...session.position };
+                    this.map.prefetchAround(this.position.x, this.position.y);
+                    this.render();
+                    this.updateZoneInfo();
+                } else if (session.throttled) {
+                    this.reportPosition();
+                }
+            } catch (error) {
+                console.error('Failed to save position:', error);
+            }
+        });
+    }
+
     calculateViewportSize() {
         const viewportRect = this.viewport.getBoundingClientRect();
         const style = window.getComputedStyle(this.viewport);
@@ -491,18 +660,82 @@ class Game {
             default: return;
         }
 
-        if (this.chunkManager.isWalkable(newX, newY)) {
+        if (this.transitioning) return;
+
+        if (this.map.isWalkable(newX, newY)) {
+            const from = { ...this.position };
             this.position.x = newX;
             this.position.y = newY;
 
             // Prefetch chunks as player moves
-            this.chunkManager.prefetchAround(newX, newY);
+            this.map.prefetchAround(newX, newY);
 
+            // Stepping through a door moves between the overworld and interiors
+            const zone = this.map.getZoneAt(newX, newY);
+            if (zone?.type === 'interior' && this.map === this.chunkManager) {
+                this.enterInterior(zone, from);
+                return;
+            }
+            if (zone?.type === 'exit' && this.overworld) {
+                this.exitInterior();
+                return;
+            }
+            if (zone?.type === 'teleporter' && this.map === this.chunkManager) {
+                // The server only accepts the jump from the teleporter itself
+                this.reportPosition(true);
+                this.teleport(zone);
+            }
+
+            this.reportPosition();
             this.render();
             this.updateZoneInfo();
         }
     }
 
+    // Load an interior and move the player inside, remembering where they
+    // stood outside the door so they can be put back on exit
+    async enterInterior(zone, from) {
+        this.transitioning = true;
+        const { chunkX, chunkY } = this.chunkManager.worldToChunk(this.position.x, this.position.y);
+
+        try {
+            const interior = await this.api.getInterior(chunkX, chunkY, zone.interior);
+            this.overworld = { position: from, fog: this.fogOfWar };
+            this.map = new InteriorMap(interior, this.chunkManager.world.tile_definitions);
+            this.fogOfWar = new FogOfWar();
+            this.position = { x: interior.spawn[0], y: interior.spawn[1] };
+        } catch (error) {
+            console.error('Failed to enter interior:', error);
+            this.position = from;
+        } finally {
+            this.transitioning = false;
+        }
+
+        this.render();
+        this.updateZoneInfo();
+    }
+
+    // Move the player to a teleporter's destination ("x,y" in world tiles)
+    teleport(zone) {
+        const [x, y] = (zone.metadata?.to || '').split(',').map(Number);
+        if (!Number.isInteger(x) || !Number.isInteger(y)) return;
+
+        this.position = { x, y };
+        this.map.prefetchAround(x, y);
+    }
+
+    // Return to the overworld tile the player entered from
+    exitInterior() {
+        this.position = this.overworld.position;
+        this.fogOfWar = this.overworld.fog;
+        this.map = this.chunkManager;
+        this.overworld = null;
+
+        this.reportPosition();
+        this.render();
+        this.updateZoneInfo();
+    }
+
     render() {
         const halfW = Math.floor(this.viewportWidth / 2);
         const halfH = Math.floor(this.viewportHeight / 2);
@@ -511,7 +744,7 @@ class Game {
         this.fogOfWar.calculateVisibility(
             this.position.x,
             this.position.y,
-            (x, y) => this.chunkManager.isOpaque(x, y)
+            (x, y) => this.map.isOpaque(x, y)
         );
 
         const rows = [];
@@ -531,7 +764,7 @@ class Game {
                         // Unexplored - show fog
                         row += `<span style="color:${this.hiddenColor}">░</span>`;
                     } else {
-                        const tile = this.chunkManager.getTile(mapX, mapY);
+                        const tile = this.map.getTile(mapX, mapY);
                         if (visibility === 'explored') {
                             // Explored but not visible - dim the color
                             const dimColor = this.dimColor(tile.color, this.exploredDim);
@@ -566,14 +799,14 @@ class Game {
 
     updateZoneInfo() {
         const zoneInfoEl = document.getElementById('zone-info');
-        const zone = this.chunkManager.getZoneAt(this.position.x, this.position.y);
+        const zone = this.map.getZoneAt(this.position.x, this.position.y);
 
         // Get current tile type and cardinal directions
-        const currentType = this.chunkManager.getTileType(this.position.x, this.position.y);
-        const northType = this.chunkManager.getTileType(this.position.x, this.position.y - 1);
-        const southType = this.chunkManager.getTileType(this.position.x, this.position.y + 1);
-        const eastType = this.chunkManager.getTileType(this.position.x + 1, this.position.y);
-        const westType = this.chunkManager.getTileType(this.position.x - 1, this.position.y);
+        const currentType = this.map.getTileType(this.position.x, this.position.y);
+        const northType = this.map.getTileType(this.position.x, this.position.y - 1);
+        const southType = this.map.getTileType(this.position.x, this.position.y + 1);
+        const eastType = this.map.getTileType(this.position.x + 1, this.position.y);
+        const westType = this.map.getTileType(this.position.x - 1, this.position.y);
 
         const tileInfo = `<p class="tile-info">Standing on: ${currentType}</p>
             <p class="tile-directions">N:${northType} S:${southType} E:${eastType} W:${westType}</p>`;
@@ -586,14 +819,14 @@ class Game {
 
         zoneInfoEl.innerHTML = `
             ${tileInfo}
-            <p class="zone-name">${zone.name}</p>
-            <p class="zone-description">${zone.description}</p>
+            <p class="zone-name">${escapeHTML(zone.name)}</p>
+            <p class="zone-description">${escapeHTML(zone.description)}</p>
             ${zone.project_id ? '<p class="hint">Press E to inspect</p>' : ''}
         `;
     }
 
     async handleInspect() {
-        const zone = this.chunkManager.getZoneAt(this.position.x, this.position.y);
+        const zone = this.map.getZoneAt(this.position.x, this.position.y);
         if (!zone || !zone.project_id) return;
 
         try {
@@ -606,21 +839,39 @@ class Game {
 
     showProjectInfo(project) {
         const el = document.getElementById('project-info');
-        const tech = project.tech_stack ? project.tech_stack.join(', ') : 'N/A';
+        const tech = project.tech_stack ? escapeHTML(project.tech_stack.join(', ')) : 'N/A';
+        const links = (project.links || []).map(link =>
+            `<a href="${escapeHTML(link.url)}" target="_blank">[${escapeHTML(link.label)}]</a>`).join('');
+        const media = (project.media || []).map(m =>
+            `<a href="${escapeHTML(m.url)}" target="_blank">[${escapeHTML(m.caption || m.file)}]</a>`).join('');
 
         el.innerHTML = `
             <div style="margin-top:15px;padding-top:15px;border-top:1px solid #3a3a3a">
-                <p class="project-title">${project.title}</p>
-                <p>${project.description}</p>
+                <p class="project-title">${escapeHTML(project.title)}</p>
+                <p>${escapeHTML(project.description)}</p>
+                ${project.body_text ? `<pre class="project-body">${escapeHTML(project.body_text)}</pre>` : ''}
                 <p class="project-tech">Tech: ${tech}</p>
-                <p class="project-tech">Year: ${project.year || 'N/A'}</p>
+                <p class="project-tech">Year: ${escapeHTML(project.year || 'N/A')}</p>
+                ${project.status === 'archived' ? '<p class="project-tech">Status: archived</p>' : ''}
                 <div class="project-links">
-                    ${project.github_url ? `<a href="${project.github_url}" target="_blank">[GitHub]</a>` : ''}
-                    ${project.live_url ? `<a href="${project.live_url}" target="_blank">[Live]</a>` : ''}
+                    ${project.github_url ? `<a href="${escapeHTML(project.github_url)}" target="_blank">[GitHub]</a>` : ''}
+                    ${project.live_url ? `<a href="${escapeHTML(project.live_url)}" target="_blank">[Live]</a>` : ''}
+                    ${links}
                 </div>
+                ${media ? `<div class="project-links">${media}</div>` : ''}
             </div>
         `;
     }
 }
 
+// escapeHTML makes text safe to put inside markup
+function escapeHTML(text) {
+    return String(text)
+        .replace(/&/g, '&amp;')
+        .replace(/</g, '&lt;')
+        .replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;')
+        .replace(/'/g, '&#39;');
+}
+
 document.addEventListener('DOMContentLoaded', () => new Game().init());